@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"graphql-comments/internal/moderation"
+	"graphql-comments/internal/storage"
+)
+
+// buildContentFilters собирает встроенные фильтры премодерации по списку имен через запятую:
+// words (запрещенные слова из wordsFile), links (больше maxLinks ссылок),
+// caps (текст заглавными) и duplicates (повтор опубликованного в посте текста)
+func buildContentFilters(names, wordsFile string, maxLinks int, store storage.Storage) ([]moderation.ContentFilter, error) {
+	var filters []moderation.ContentFilter
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case "words":
+			if wordsFile == "" {
+				return nil, fmt.Errorf("фильтру words нужен -banned-words-file")
+			}
+			words, err := moderation.LoadBannedWords(wordsFile)
+			if err != nil {
+				return nil, err
+			}
+			filters = append(filters, words)
+		case "links":
+			filters = append(filters, moderation.LinkLimit{Max: maxLinks})
+		case "caps":
+			filters = append(filters, moderation.DefaultAllCaps())
+		case "duplicates":
+			filters = append(filters, moderation.Duplicate{Comments: store, MinLength: 20})
+		default:
+			return nil, fmt.Errorf("неизвестный фильтр: %s", name)
+		}
+	}
+	return filters, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"graphql-comments/internal/persisted"
+	"graphql-comments/internal/storage"
+)
+
+// persistedCleanupInterval - как часто удалять устаревшие persisted запросы из PostgreSQL
+const persistedCleanupInterval = time.Hour
+
+// persistedFlags - значения флагов -persisted-queries*
+type persistedFlags struct {
+	mode       string
+	store      string
+	cacheSize  int
+	maxEntries int
+	ttl        time.Duration
+	manifest   string
+}
+
+// buildPersistedQueries настраивает persisted запросы: off - выключены, apq - регистрация
+// по хэшу в LRU кэше (с postgres - поверх таблицы persisted_queries не больше maxEntries
+// записей, каждая живет ttl), allowlist - только
+// операции манифеста. Операции манифеста в режиме apq доступны сразу
+func buildPersistedQueries(flags persistedFlags, store storage.Storage) (*persisted.Queries, error) {
+	var manifest map[string]string
+	if flags.manifest != "" {
+		var err error
+		if manifest, err = persisted.LoadManifest(flags.manifest); err != nil {
+			return nil, err
+		}
+	}
+
+	switch flags.mode {
+	case "off":
+		return nil, nil
+	case "allowlist":
+		if manifest == nil {
+			return nil, fmt.Errorf("режиму allowlist нужен -persisted-queries-manifest")
+		}
+		return persisted.NewAllowlist(manifest), nil
+	case "apq":
+	default:
+		return nil, fmt.Errorf("неизвестный режим persisted запросов: %s", flags.mode)
+	}
+
+	if flags.cacheSize <= 0 {
+		return nil, fmt.Errorf("-persisted-queries-cache должен быть больше 0")
+	}
+
+	var next persisted.Store
+	switch flags.store {
+	case "memory":
+	case "postgres":
+		pgStorage, ok := store.(*storage.PostgresStorage)
+		if !ok {
+			return nil, fmt.Errorf("хранилище persisted запросов postgres доступно только с PostgreSQL хранилищем")
+		}
+		if flags.maxEntries <= 0 || flags.ttl <= 0 {
+			return nil, fmt.Errorf("-persisted-queries-max и -persisted-queries-ttl должны быть больше 0")
+		}
+		pgQueries := persisted.NewPostgresStore(pgStorage.DB(), flags.maxEntries)
+		go pgQueries.RunCleanup(context.Background(), flags.ttl, persistedCleanupInterval)
+		next = pgQueries
+	default:
+		return nil, fmt.Errorf("неизвестное хранилище persisted запросов: %s", flags.store)
+	}
+	return persisted.NewAPQ(persisted.NewLRUStore(flags.cacheSize, next), manifest), nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"graphql-comments/internal/ratelimit"
+	"graphql-comments/internal/storage"
+)
+
+// rateLimitCleanupInterval - как часто удалять восполненные корзины из PostgreSQL
+const rateLimitCleanupInterval = 10 * time.Minute
+
+// rateLimitFlags - значения флагов -rate-limit-*
+type rateLimitFlags struct {
+	store        string
+	requests     string
+	posts        string
+	comments     string
+	postComments string
+	// trustedProxies - сети прокси через запятую, за которыми IP клиента берется из X-Forwarded-For
+	trustedProxies string
+}
+
+// buildRateLimiter разбирает лимиты и создает ограничитель с хранилищем корзин
+// в памяти (memory) или в таблице rate_limits (postgres, общее для всех реплик)
+func buildRateLimiter(flags rateLimitFlags, store storage.Storage) (*ratelimit.Limiter, ratelimit.Limits, error) {
+	var limits ratelimit.Limits
+	for _, item := range []struct {
+		value string
+		limit *ratelimit.Limit
+	}{
+		{flags.requests, &limits.Requests},
+		{flags.posts, &limits.Posts},
+		{flags.comments, &limits.Comments},
+		{flags.postComments, &limits.PostComments},
+	} {
+		limit, err := ratelimit.ParseLimit(item.value)
+		if err != nil {
+			return nil, limits, err
+		}
+		*item.limit = limit
+	}
+
+	proxies, err := ratelimit.ParseTrustedProxies(flags.trustedProxies)
+	if err != nil {
+		return nil, limits, err
+	}
+
+	var limiter *ratelimit.Limiter
+	switch flags.store {
+	case "memory":
+		limiter = ratelimit.New(ratelimit.NewMemoryStore(), limits)
+	case "postgres":
+		pgStorage, ok := store.(*storage.PostgresStorage)
+		if !ok {
+			return nil, limits, fmt.Errorf("хранилище корзин postgres доступно только с PostgreSQL хранилищем")
+		}
+		buckets := ratelimit.NewPostgresStore(pgStorage.DB())
+		go buckets.RunCleanup(context.Background(), rateLimitCleanupInterval)
+		limiter = ratelimit.New(buckets, limits)
+	default:
+		return nil, limits, fmt.Errorf("неизвестное хранилище корзин: %s", flags.store)
+	}
+	limiter.TrustProxies(proxies)
+	return limiter, limits, nil
+}
@@ -8,3 +8,5 @@ require (
 )
 
 require github.com/lib/pq v1.11.1
+
+require github.com/gorilla/websocket v1.5.3
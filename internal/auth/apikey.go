@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// APIKeyHeader - заголовок, в котором передается статический API ключ
+const APIKeyHeader = "X-API-Key"
+
+// APIKeys - аутентификатор по статическим API ключам (для сервисов и скриптов).
+// Ключи хранятся только в виде SHA-256, поиск по хэшу не зависит от совпавших символов ключа
+type APIKeys struct {
+	viewers map[[sha256.Size]byte]*Viewer
+}
+
+// NewAPIKeys создает аутентификатор из пар ключ -> ID пользователя с ролью RoleUser
+func NewAPIKeys(keys map[string]string) *APIKeys {
+	a := &APIKeys{viewers: make(map[[sha256.Size]byte]*Viewer, len(keys))}
+	for key, userID := range keys {
+		a.add(key, userID, RoleUser)
+	}
+	return a
+}
+
+// add регистрирует ключ пользователя
+func (a *APIKeys) add(key, userID string, role Role) {
+	a.viewers[sha256.Sum256([]byte(key))] = &Viewer{ID: userID, Name: userID, Method: MethodAPIKey, Role: role}
+}
+
+// LoadAPIKeys читает файл ключей: по строке "ID_пользователя ключ [роль]",
+// пустые строки и строки с # пропускаются
+func LoadAPIKeys(path string) (*APIKeys, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	a := &APIKeys{viewers: make(map[[sha256.Size]byte]*Viewer)}
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 && len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: ожидали \"ID_пользователя ключ [роль]\"", path, lineNo)
+		}
+		role := RoleUser
+		if len(fields) == 3 {
+			var err error
+			if role, err = ParseRole(fields[2]); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+			}
+		}
+		if _, exists := a.viewers[sha256.Sum256([]byte(fields[1]))]; exists {
+			return nil, fmt.Errorf("%s:%d: ключ повторяется", path, lineNo)
+		}
+		a.add(fields[1], fields[0], role)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Authenticate проверяет заголовок X-API-Key
+func (a *APIKeys) Authenticate(r *http.Request) (*Viewer, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		return nil, nil
+	}
+
+	viewer, exists := a.viewers[sha256.Sum256([]byte(key))]
+	if !exists {
+		return nil, ErrInvalidCredentials
+	}
+	viewerCopy := *viewer
+	return &viewerCopy, nil
+}
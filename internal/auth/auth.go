@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Способы аутентификации, которыми был получен Viewer
+const (
+	MethodJWT    = "jwt"
+	MethodAPIKey = "api_key"
+)
+
+// CodeUnauthenticated - код ошибки в extensions.code при неверных учетных данных
+const CodeUnauthenticated = "UNAUTHENTICATED"
+
+// ErrInvalidCredentials - переданные учетные данные не прошли проверку
+var ErrInvalidCredentials = errors.New("недействительные учетные данные")
+
+// Viewer - аутентифицированный пользователь текущего запроса
+type Viewer struct {
+	// ID - стабильный идентификатор (sub токена или владелец API ключа),
+	// он же автор создаваемых постов и комментариев
+	ID string `json:"id"`
+	// Name - отображаемое имя, по умолчанию совпадает с ID
+	Name string `json:"name"`
+	// Method - способ аутентификации: MethodJWT или MethodAPIKey
+	Method string `json:"authMethod"`
+	// Role - роль пользователя, по умолчанию RoleUser
+	Role Role `json:"role"`
+}
+
+// HasRole сообщает, что у пользователя роль не ниже min (nil - анонимный запрос без прав).
+// Пустая роль считается RoleUser, как в ParseRole
+func (v *Viewer) HasRole(min Role) bool {
+	if v == nil {
+		return false
+	}
+	if v.Role == "" {
+		return RoleUser.AtLeast(min)
+	}
+	return v.Role.AtLeast(min)
+}
+
+// Authenticator проверяет учетные данные запроса одного вида.
+// Если их нет, возвращает nil, nil - тогда пробуется следующий аутентификатор
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Viewer, error)
+}
+
+// viewerKey - ключ контекста, под которым хранится Viewer
+type viewerKey struct{}
+
+// WithViewer добавляет пользователя в контекст
+func WithViewer(ctx context.Context, viewer *Viewer) context.Context {
+	return context.WithValue(ctx, viewerKey{}, viewer)
+}
+
+// ViewerFromContext возвращает пользователя запроса или nil для анонимного запроса
+func ViewerFromContext(ctx context.Context) *Viewer {
+	if ctx == nil {
+		return nil
+	}
+	viewer, _ := ctx.Value(viewerKey{}).(*Viewer)
+	return viewer
+}
+
+// Middleware проверяет учетные данные запроса аутентификаторами по очереди
+// и кладет найденного пользователя в контекст. Запрос без учетных данных
+// проходит анонимно, с неверными - отклоняется с кодом 401
+func Middleware(authenticators ...Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, authenticator := range authenticators {
+				viewer, err := authenticator.Authenticate(r)
+				if err != nil {
+					writeUnauthenticated(w, err)
+					return
+				}
+				if viewer != nil {
+					if viewer.Name == "" {
+						viewer.Name = viewer.ID
+					}
+					if viewer.Role == "" {
+						viewer.Role = RoleUser
+					}
+					r = r.WithContext(WithViewer(r.Context(), viewer))
+					break
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeUnauthenticated отвечает ошибкой в формате GraphQL, чтобы клиенты
+// разбирали ее так же, как ошибки резолверов
+func writeUnauthenticated(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]interface{}{{
+			"message":    err.Error(),
+			"extensions": map[string]interface{}{"code": CodeUnauthenticated},
+		}},
+	})
+}
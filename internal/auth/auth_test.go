@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("тестовый-секрет-для-hs256")
+
+// signToken собирает JWT с заданными заголовком и полями
+func signToken(t *testing.T, header, claims map[string]interface{}, sign func(input string) []byte) string {
+	t.Helper()
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("Ошибка кодирования: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	input := encode(header) + "." + encode(claims)
+	return input + "." + base64.RawURLEncoding.EncodeToString(sign(input))
+}
+
+func hs256(secret []byte) func(string) []byte {
+	return func(input string) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(input))
+		return mac.Sum(nil)
+	}
+}
+
+func rs256(t *testing.T, key *rsa.PrivateKey) func(string) []byte {
+	return func(input string) []byte {
+		digest := sha256.Sum256([]byte(input))
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("Ошибка подписи: %v", err)
+		}
+		return signature
+	}
+}
+
+func TestJWT_Verify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Ошибка генерации ключа: %v", err)
+	}
+	jwks := fmt.Sprintf(`{"keys": [
+		{"kty": "oct", "kid": "hmac", "alg": "HS256", "k": %q},
+		{"kty": "RSA", "kid": "rsa", "alg": "RS256", "n": %q, "e": %q}
+	]}`,
+		base64.RawURLEncoding.EncodeToString(testSecret),
+		base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()))
+
+	verifier, err := ParseJWKS([]byte(jwks), JWTConfig{Issuer: "comments", Audience: "api"})
+	if err != nil {
+		t.Fatalf("Ошибка разбора JWKS: %v", err)
+	}
+	now := time.Now()
+	claims := func(modify func(map[string]interface{})) map[string]interface{} {
+		c := map[string]interface{}{
+			"sub": "user_1", "name": "Алиса", "iss": "comments", "aud": []string{"api"},
+			"exp": now.Add(time.Hour).Unix(),
+		}
+		if modify != nil {
+			modify(c)
+		}
+		return c
+	}
+	hmacHeader := map[string]interface{}{"alg": "HS256", "kid": "hmac"}
+	rsaHeader := map[string]interface{}{"alg": "RS256", "kid": "rsa"}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"HS256", signToken(t, hmacHeader, claims(nil), hs256(testSecret)), false},
+		{"RS256", signToken(t, rsaHeader, claims(nil), rs256(t, rsaKey)), false},
+		{"чужой секрет", signToken(t, hmacHeader, claims(nil), hs256([]byte("другой"))), true},
+		{"истек", signToken(t, hmacHeader, claims(func(c map[string]interface{}) {
+			c["exp"] = now.Add(-time.Hour).Unix()
+		}), hs256(testSecret)), true},
+		{"еще не действует", signToken(t, hmacHeader, claims(func(c map[string]interface{}) {
+			c["nbf"] = now.Add(time.Hour).Unix()
+		}), hs256(testSecret)), true},
+		{"без exp", signToken(t, hmacHeader, claims(func(c map[string]interface{}) {
+			delete(c, "exp")
+		}), hs256(testSecret)), true},
+		{"чужой издатель", signToken(t, hmacHeader, claims(func(c map[string]interface{}) {
+			c["iss"] = "other"
+		}), hs256(testSecret)), true},
+		{"чужой получатель", signToken(t, hmacHeader, claims(func(c map[string]interface{}) {
+			c["aud"] = "other"
+		}), hs256(testSecret)), true},
+		{"alg none", signToken(t, map[string]interface{}{"alg": "none", "kid": "hmac"}, claims(nil),
+			func(string) []byte { return nil }), true},
+		// Публичный RSA ключ в роли HMAC секрета не должен подходить
+		{"подмена алгоритма", signToken(t, map[string]interface{}{"alg": "HS256", "kid": "rsa"}, claims(nil),
+			hs256(rsaKey.N.Bytes())), true},
+		{"мусор", "abc.def", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := verifier.Verify(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Ожидали ошибку: %v, получили %v", tt.wantErr, err)
+			}
+			if err == nil && got.Subject != "user_1" {
+				t.Errorf("Ожидали sub user_1, получили %s", got.Subject)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	verifier, err := ParseJWKS([]byte(fmt.Sprintf(`{"keys": [{"kty": "oct", "k": %q}]}`,
+		base64.RawURLEncoding.EncodeToString(testSecret))), JWTConfig{})
+	if err != nil {
+		t.Fatalf("Ошибка разбора JWKS: %v", err)
+	}
+	keys := NewAPIKeys(map[string]string{"secret-key": "robot"})
+
+	var seen *Viewer
+	handler := Middleware(verifier, keys)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = ViewerFromContext(r.Context())
+	}))
+
+	token := signToken(t, map[string]interface{}{"alg": "HS256"},
+		map[string]interface{}{"sub": "user_1", "exp": time.Now().Add(time.Hour).Unix()}, hs256(testSecret))
+
+	tests := []struct {
+		name       string
+		header     string
+		value      string
+		wantStatus int
+		wantViewer string
+		wantMethod string
+	}{
+		{"аноним", "", "", http.StatusOK, "", ""},
+		{"JWT", "Authorization", "Bearer " + token, http.StatusOK, "user_1", MethodJWT},
+		{"API ключ", APIKeyHeader, "secret-key", http.StatusOK, "robot", MethodAPIKey},
+		{"неверный токен", "Authorization", "Bearer " + token + "x", http.StatusUnauthorized, "", ""},
+		{"неверный ключ", APIKeyHeader, "wrong", http.StatusUnauthorized, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = nil
+			req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Ожидали статус %d, получили %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantViewer == "" {
+				if seen != nil {
+					t.Errorf("Ожидали анонимный запрос, получили %+v", seen)
+				}
+				return
+			}
+			if seen == nil || seen.ID != tt.wantViewer || seen.Method != tt.wantMethod || seen.Name != tt.wantViewer {
+				t.Errorf("Неожиданный пользователь: %+v", seen)
+			}
+		})
+	}
+}
+
+func TestLoadAPIKeys_Roles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api_keys.txt")
+	data := "# ID ключ роль\nrobot robot-key\nmoder moder-key moderator\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("Ошибка записи файла: %v", err)
+	}
+	keys, err := LoadAPIKeys(path)
+	if err != nil {
+		t.Fatalf("Ошибка загрузки ключей: %v", err)
+	}
+
+	for key, want := range map[string]Role{"robot-key": RoleUser, "moder-key": RoleModerator} {
+		req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		req.Header.Set(APIKeyHeader, key)
+		viewer, err := keys.Authenticate(req)
+		if err != nil || viewer == nil || viewer.Role != want {
+			t.Errorf("Ожидали роль %s для %s, получили %+v, %v", want, key, viewer, err)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("root root-key superuser\n"), 0o600); err != nil {
+		t.Fatalf("Ошибка записи файла: %v", err)
+	}
+	if _, err := LoadAPIKeys(path); err == nil {
+		t.Error("Ожидали ошибку для неизвестной роли")
+	}
+}
@@ -0,0 +1,286 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Поддерживаемые алгоритмы подписи JWT
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+)
+
+// clockSkew - допустимое расхождение часов при проверке exp и nbf
+const clockSkew = time.Minute
+
+// jwk - ключ из JWKS файла: "oct" для HS256 или "RSA" для RS256
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// verificationKey - разобранный ключ проверки подписи
+type verificationKey struct {
+	alg    string
+	secret []byte
+	public *rsa.PublicKey
+}
+
+// JWTConfig - параметры проверки токенов
+type JWTConfig struct {
+	// Issuer и Audience, если заданы, должны совпасть с iss и aud токена
+	Issuer   string
+	Audience string
+}
+
+// JWT - аутентификатор по токену из заголовка Authorization: Bearer.
+// Ключи берутся из локального JWKS файла, алгоритм ключа фиксирован,
+// поэтому токен не может подменить RS256 на HS256 или none
+type JWT struct {
+	config JWTConfig
+	keys   map[string]verificationKey
+	now    func() time.Time
+}
+
+// LoadJWKS читает ключи проверки из JWKS файла
+func LoadJWKS(path string, config JWTConfig) (*JWT, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(data, config)
+}
+
+// ParseJWKS разбирает JWKS документ {"keys": [...]}
+func ParseJWKS(data []byte, config JWTConfig) (*JWT, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("некорректный JWKS: %w", err)
+	}
+	if len(set.Keys) == 0 {
+		return nil, errors.New("в JWKS нет ключей")
+	}
+
+	j := &JWT{config: config, keys: make(map[string]verificationKey), now: time.Now}
+	for _, key := range set.Keys {
+		parsed, err := parseJWK(key)
+		if err != nil {
+			return nil, fmt.Errorf("ключ JWKS %q: %w", key.Kid, err)
+		}
+		if _, exists := j.keys[key.Kid]; exists {
+			return nil, fmt.Errorf("ключ JWKS %q повторяется", key.Kid)
+		}
+		j.keys[key.Kid] = parsed
+	}
+	return j, nil
+}
+
+// parseJWK проверяет, что алгоритм соответствует типу ключа, и декодирует его
+func parseJWK(key jwk) (verificationKey, error) {
+	switch key.Kty {
+	case "oct":
+		if key.Alg != "" && key.Alg != AlgHS256 {
+			return verificationKey{}, fmt.Errorf("алгоритм %s не поддерживается для ключа oct", key.Alg)
+		}
+		secret, err := base64.RawURLEncoding.DecodeString(key.K)
+		if err != nil || len(secret) == 0 {
+			return verificationKey{}, errors.New("некорректное поле k")
+		}
+		return verificationKey{alg: AlgHS256, secret: secret}, nil
+
+	case "RSA":
+		if key.Alg != "" && key.Alg != AlgRS256 {
+			return verificationKey{}, fmt.Errorf("алгоритм %s не поддерживается для ключа RSA", key.Alg)
+		}
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil || len(n) == 0 {
+			return verificationKey{}, errors.New("некорректное поле n")
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return verificationKey{}, errors.New("некорректное поле e")
+		}
+		public := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+		return verificationKey{alg: AlgRS256, public: public}, nil
+
+	default:
+		return verificationKey{}, fmt.Errorf("тип ключа %q не поддерживается", key.Kty)
+	}
+}
+
+// jwtClaims - используемые поля токена
+type jwtClaims struct {
+	Subject           string   `json:"sub"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+	Role              string   `json:"role"`
+	Issuer            string   `json:"iss"`
+	Audience          audience `json:"aud"`
+	ExpiresAt         *int64   `json:"exp"`
+	NotBefore         *int64   `json:"nbf"`
+}
+
+// audience - поле aud, которое бывает строкой или массивом строк
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// Authenticate проверяет заголовок Authorization: Bearer <token>
+func (j *JWT) Authenticate(r *http.Request) (*Viewer, error) {
+	header := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return nil, nil
+	}
+
+	claims, err := j.Verify(strings.TrimSpace(token))
+	if err != nil {
+		return nil, err
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = claims.PreferredUsername
+	}
+	// Неизвестная роль в подписанном токене - ошибка выдачи токена, а не повод понизить права молча
+	role, err := ParseRole(claims.Role)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return &Viewer{ID: claims.Subject, Name: name, Method: MethodJWT, Role: role}, nil
+}
+
+// Verify проверяет подпись и срок действия токена и возвращает его поля.
+// Причина отказа не раскрывается клиенту: любая ошибка - ErrInvalidCredentials
+func (j *JWT) Verify(token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidCredentials
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	key, ok := j.keyFor(header.Kid)
+	if !ok || key.alg != header.Alg {
+		return nil, ErrInvalidCredentials
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if !key.verify(parts[0]+"."+parts[1], signature) {
+		return nil, ErrInvalidCredentials
+	}
+
+	claims := &jwtClaims{}
+	if err := decodeSegment(parts[1], claims); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if err := j.validate(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// keyFor выбирает ключ по kid; без kid подходит только единственный ключ
+func (j *JWT) keyFor(kid string) (verificationKey, bool) {
+	if key, ok := j.keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, true
+		}
+	}
+	return verificationKey{}, false
+}
+
+// validate проверяет срок действия, издателя и получателя токена
+func (j *JWT) validate(claims *jwtClaims) error {
+	now := j.now()
+	if claims.Subject == "" || claims.ExpiresAt == nil {
+		return ErrInvalidCredentials
+	}
+	if now.After(time.Unix(*claims.ExpiresAt, 0).Add(clockSkew)) {
+		return ErrInvalidCredentials
+	}
+	if claims.NotBefore != nil && now.Add(clockSkew).Before(time.Unix(*claims.NotBefore, 0)) {
+		return ErrInvalidCredentials
+	}
+	if j.config.Issuer != "" && claims.Issuer != j.config.Issuer {
+		return ErrInvalidCredentials
+	}
+	if j.config.Audience != "" {
+		for _, aud := range claims.Audience {
+			if aud == j.config.Audience {
+				return nil
+			}
+		}
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
+// verify проверяет подпись signingInput
+func (k verificationKey) verify(signingInput string, signature []byte) bool {
+	switch k.alg {
+	case AlgHS256:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write([]byte(signingInput))
+		return hmac.Equal(signature, mac.Sum(nil))
+	case AlgRS256:
+		digest := sha256.Sum256([]byte(signingInput))
+		return rsa.VerifyPKCS1v15(k.public, crypto.SHA256, digest[:], signature) == nil
+	default:
+		return false
+	}
+}
+
+// decodeSegment декодирует base64url-JSON часть токена
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
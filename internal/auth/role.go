@@ -0,0 +1,37 @@
+package auth
+
+import "fmt"
+
+// Role - роль пользователя. Роли упорядочены: каждая следующая включает права предыдущей
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+// roleLevels - порядок ролей для сравнения
+var roleLevels = map[Role]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// ParseRole возвращает роль по имени (пустое имя - RoleUser)
+func ParseRole(name string) (Role, error) {
+	if name == "" {
+		return RoleUser, nil
+	}
+	role := Role(name)
+	if _, ok := roleLevels[role]; !ok {
+		return "", fmt.Errorf("неизвестная роль: %s", name)
+	}
+	return role, nil
+}
+
+// AtLeast сообщает, что роль не ниже min. Неизвестная роль не дает никаких прав
+func (r Role) AtLeast(min Role) bool {
+	level, ok := roleLevels[r]
+	return ok && level >= roleLevels[min]
+}
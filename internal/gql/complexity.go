@@ -0,0 +1,385 @@
+package gql
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// defaultListSize - во сколько раз умножается стоимость полей внутри списка без аргумента размера
+const defaultListSize = 10
+
+// maxCost - потолок счетчиков стоимости, чтобы умножение не переполнялось
+const maxCost = math.MaxInt32
+
+// sizeArguments - аргументы, задающие размер возвращаемого списка
+var sizeArguments = []string{"first", "last", "limit"}
+
+// QueryLimits - ограничения запроса, которые проверяются до выполнения.
+// Нулевое поле - без ограничения
+type QueryLimits struct {
+	// MaxDepth - наибольшая вложенность полей (поле верхнего уровня - глубина 1)
+	MaxDepth int
+	// MaxComplexity - наибольшая стоимость: каждое поле стоит 1, поля внутри списка
+	// умножаются на его размер (first, last или limit, без них - defaultListSize).
+	// Рекурсивный список без размера (replies внутри replies) не умножает стоимость:
+	// ответы всех уровней - одна ветка, ее размер оценен на первом уровне
+	MaxComplexity int
+	// MaxAliases - наибольшее число псевдонимов во всем запросе с учетом фрагментов
+	MaxAliases int
+}
+
+// DefaultQueryLimits возвращает ограничения по умолчанию: дерево комментариев поста
+// с ветками ответов в десяток уровней проходит, запрос на сотни уровней - нет
+func DefaultQueryLimits() QueryLimits {
+	return QueryLimits{
+		MaxDepth:      15,
+		MaxComplexity: 10000,
+		MaxAliases:    30,
+	}
+}
+
+// QueryCost - стоимость запроса, посчитанная по тексту до выполнения.
+// Поля интроспекции (__schema, __type, __typename) не учитываются
+type QueryCost struct {
+	Depth      int `json:"depth"`
+	Complexity int `json:"complexity"`
+	Aliases    int `json:"aliases"`
+}
+
+// queryLimitError - запрос превышает одно из ограничений
+type queryLimitError struct {
+	limit string
+	value int
+	max   int
+}
+
+func (e *queryLimitError) Error() string {
+	return fmt.Sprintf("запрос слишком сложный: %s %d при ограничении %d", e.limit, e.value, e.max)
+}
+
+// Extensions возвращает код ошибки, превышенное ограничение и значения
+func (e *queryLimitError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":  CodeQueryTooComplex,
+		"limit": e.limit,
+		"value": e.value,
+		"max":   e.max,
+	}
+}
+
+// Check возвращает ошибку QUERY_TOO_COMPLEX, если стоимость превышает ограничения
+func (l QueryLimits) Check(cost QueryCost) error {
+	for _, check := range []struct {
+		limit      string
+		value, max int
+	}{
+		{"depth", cost.Depth, l.MaxDepth},
+		{"complexity", cost.Complexity, l.MaxComplexity},
+		{"aliases", cost.Aliases, l.MaxAliases},
+	} {
+		if check.max > 0 && check.value > check.max {
+			return &queryLimitError{limit: check.limit, value: check.value, max: check.max}
+		}
+	}
+	return nil
+}
+
+// checkQuery считает стоимость запроса и сверяет ее с ограничениями. Если запрос
+// превышает ограничения, возвращает готовый результат с ошибкой - выполнять его не нужно.
+// Запрос, который не удалось разобрать, пропускается: ошибку вернет graphql.Do
+func checkQuery(params graphql.Params, limits QueryLimits) (QueryCost, *graphql.Result) {
+	cost, ok := AnalyzeQuery(&params.Schema, params.RequestString, params.OperationName, params.VariableValues)
+	if !ok {
+		return cost, nil
+	}
+	if err := limits.Check(cost); err != nil {
+		return cost, withCost(errorResult(err), cost)
+	}
+	return cost, nil
+}
+
+// errorResult - ответ с одной ошибкой для запроса, который не выполнялся
+func errorResult(err error) *graphql.Result {
+	formatted := gqlerrors.FormattedError{Message: err.Error()}
+	if extended, ok := err.(gqlerrors.ExtendedError); ok {
+		formatted.Extensions = extended.Extensions()
+	}
+	return &graphql.Result{Errors: []gqlerrors.FormattedError{formatted}}
+}
+
+// withCost добавляет стоимость запроса в extensions.cost ответа
+func withCost(result *graphql.Result, cost QueryCost) *graphql.Result {
+	if result.Extensions == nil {
+		result.Extensions = make(map[string]interface{})
+	}
+	result.Extensions["cost"] = cost
+	return result
+}
+
+// AnalyzeQuery считает стоимость операции operationName (или единственной операции) запроса.
+// Возвращает false, если запрос не разбирается или операция не найдена
+func AnalyzeQuery(schema *graphql.Schema, query, operationName string, variables map[string]interface{}) (QueryCost, bool) {
+	document, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return QueryCost{}, false
+	}
+
+	analyzer := &costAnalyzer{
+		schema:    schema,
+		variables: variables,
+		defaults:  make(map[string]ast.Value),
+		fragments: make(map[string]*ast.FragmentDefinition),
+		memo:      make(map[fragmentKey]QueryCost),
+	}
+	var operation *ast.OperationDefinition
+	operations := 0
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.OperationDefinition:
+			operations++
+			if operationName == "" || (definition.Name != nil && definition.Name.Value == operationName) {
+				operation = definition
+			}
+		case *ast.FragmentDefinition:
+			analyzer.fragments[definition.Name.Value] = definition
+		}
+	}
+	// Без имени операции запрос должен содержать ровно одну операцию
+	if operation == nil || (operationName == "" && operations > 1) {
+		return QueryCost{}, false
+	}
+
+	root := rootType(schema, operation.Operation)
+	if root == nil {
+		return QueryCost{}, false
+	}
+	for _, definition := range operation.VariableDefinitions {
+		if definition.DefaultValue != nil {
+			analyzer.defaults[definition.Variable.Name.Value] = definition.DefaultValue
+		}
+	}
+	return analyzer.selectionSetCost(operation.SelectionSet, root, 0, false), true
+}
+
+// rootType возвращает корневой тип операции
+func rootType(schema *graphql.Schema, operation string) *graphql.Object {
+	switch operation {
+	case ast.OperationTypeQuery:
+		return schema.QueryType()
+	case ast.OperationTypeMutation:
+		return schema.MutationType()
+	case ast.OperationTypeSubscription:
+		return schema.SubscriptionType()
+	}
+	return nil
+}
+
+// fragmentKey - стоимость фрагмента зависит от размера страницы, переданного из соединения,
+// и от того, раскрыт ли он внутри рекурсивного списка
+type fragmentKey struct {
+	name      string
+	inherited int
+	recursive bool
+}
+
+// costAnalyzer обходит дерево запроса снизу вверх. Стоимость фрагмента запоминается,
+// поэтому фрагменты, вложенные друг в друга много раз, не обходятся экспоненциально долго
+type costAnalyzer struct {
+	schema    *graphql.Schema
+	variables map[string]interface{}
+	defaults  map[string]ast.Value
+	fragments map[string]*ast.FragmentDefinition
+	memo      map[fragmentKey]QueryCost
+}
+
+// selectionSetCost считает стоимость набора полей типа parent. inherited - размер страницы
+// соединения, который получают вложенные списки без своего аргумента размера (edges).
+// recursive - набор выбран внутри рекурсивного списка, например replies
+func (a *costAnalyzer) selectionSetCost(set *ast.SelectionSet, parent graphql.Type, inherited int, recursive bool) QueryCost {
+	var total QueryCost
+	if set == nil {
+		return total
+	}
+
+	for _, selection := range set.Selections {
+		var cost QueryCost
+		switch selection := selection.(type) {
+		case *ast.Field:
+			cost = a.fieldCost(selection, parent, inherited, recursive)
+		case *ast.InlineFragment:
+			fragmentType := parent
+			if selection.TypeCondition != nil {
+				if named := a.schema.Type(selection.TypeCondition.Name.Value); named != nil {
+					fragmentType = named
+				}
+			}
+			cost = a.selectionSetCost(selection.SelectionSet, fragmentType, inherited, recursive)
+		case *ast.FragmentSpread:
+			cost = a.fragmentCost(selection.Name.Value, inherited, recursive)
+		}
+		total.Depth = max(total.Depth, cost.Depth)
+		total.Complexity = addCost(total.Complexity, cost.Complexity)
+		total.Aliases = addCost(total.Aliases, cost.Aliases)
+	}
+	return total
+}
+
+// fragmentCost считает стоимость именованного фрагмента. Циклы между фрагментами
+// здесь стоят 0 - такой запрос отклонит валидация
+func (a *costAnalyzer) fragmentCost(name string, inherited int, recursive bool) QueryCost {
+	key := fragmentKey{name: name, inherited: inherited, recursive: recursive}
+	if cost, ok := a.memo[key]; ok {
+		return cost
+	}
+	fragment := a.fragments[name]
+	if fragment == nil || fragment.TypeCondition == nil {
+		return QueryCost{}
+	}
+
+	a.memo[key] = QueryCost{}
+	cost := a.selectionSetCost(fragment.SelectionSet, a.schema.Type(fragment.TypeCondition.Name.Value), inherited, recursive)
+	a.memo[key] = cost
+	return cost
+}
+
+// fieldCost считает стоимость поля с вложенными полями
+func (a *costAnalyzer) fieldCost(field *ast.Field, parent graphql.Type, inherited int, recursive bool) QueryCost {
+	name := field.Name.Value
+	if strings.HasPrefix(name, "__") {
+		return QueryCost{}
+	}
+	definition := fieldDefinition(parent, name)
+	if definition == nil {
+		// Неизвестное поле отклонит валидация
+		return QueryCost{}
+	}
+
+	cost := QueryCost{Depth: 1, Complexity: 1}
+	if field.Alias != nil {
+		cost.Aliases = 1
+	}
+	if field.SelectionSet == nil {
+		return cost
+	}
+
+	childType, _ := graphql.GetNamed(definition.Type).(graphql.Type)
+	// Список того же типа, что и родитель: replies у комментария
+	selfList := isListType(definition.Type) && childType == parent
+
+	size, hasSize := a.sizeArgument(field, definition)
+	multiplier, childInherited := 1, 0
+	switch {
+	case isListType(definition.Type) && hasSize:
+		multiplier = size
+	case isListType(definition.Type) && inherited > 0:
+		multiplier = inherited
+	case selfList && recursive:
+		// Вложенные уровни ветки уже учтены множителем ее первого уровня
+		multiplier = 1
+	case isListType(definition.Type):
+		multiplier = defaultListSize
+	case hasSize:
+		// Соединение: размер страницы определяет число ребер
+		childInherited = size
+	}
+
+	children := a.selectionSetCost(field.SelectionSet, childType, childInherited, selfList)
+	cost.Depth += children.Depth
+	cost.Complexity = addCost(cost.Complexity, mulCost(multiplier, children.Complexity))
+	cost.Aliases = addCost(cost.Aliases, children.Aliases)
+	return cost
+}
+
+// sizeArgument возвращает размер списка из аргумента запроса или значения по умолчанию в схеме
+func (a *costAnalyzer) sizeArgument(field *ast.Field, definition *graphql.FieldDefinition) (int, bool) {
+	for _, name := range sizeArguments {
+		for _, argument := range field.Arguments {
+			if argument.Name.Value == name {
+				if size, ok := a.intValue(argument.Value); ok {
+					return size, true
+				}
+			}
+		}
+		for _, argument := range definition.Args {
+			if argument.Name() == name {
+				if size, ok := argument.DefaultValue.(int); ok {
+					return max(size, 0), true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// intValue возвращает целое значение литерала или переменной запроса
+func (a *costAnalyzer) intValue(value ast.Value) (int, bool) {
+	switch value := value.(type) {
+	case *ast.IntValue:
+		n, err := strconv.Atoi(value.Value)
+		if err != nil {
+			return maxCost, true
+		}
+		return min(max(n, 0), maxCost), true
+	case *ast.Variable:
+		name := value.Name.Value
+		if provided, ok := a.variables[name]; ok {
+			switch n := provided.(type) {
+			case float64:
+				return int(min(max(n, 0), maxCost)), true
+			case int:
+				return min(max(n, 0), maxCost), true
+			}
+			return 0, false
+		}
+		if defaultValue, ok := a.defaults[name]; ok {
+			return a.intValue(defaultValue)
+		}
+	}
+	return 0, false
+}
+
+// fieldDefinition ищет поле в объекте или интерфейсе
+func fieldDefinition(parent graphql.Type, name string) *graphql.FieldDefinition {
+	switch parent := parent.(type) {
+	case *graphql.Object:
+		return parent.Fields()[name]
+	case *graphql.Interface:
+		return parent.Fields()[name]
+	}
+	return nil
+}
+
+// isListType сообщает, что поле возвращает список (возможно, обязательный)
+func isListType(fieldType graphql.Type) bool {
+	if nonNull, ok := fieldType.(*graphql.NonNull); ok {
+		fieldType = nonNull.OfType
+	}
+	_, ok := fieldType.(*graphql.List)
+	return ok
+}
+
+// addCost складывает счетчики с насыщением на maxCost
+func addCost(a, b int) int {
+	return min(a+b, maxCost)
+}
+
+// mulCost умножает счетчики с насыщением на maxCost
+func mulCost(a, b int) int {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	if a > maxCost/b {
+		return maxCost
+	}
+	return a * b
+}
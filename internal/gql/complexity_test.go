@@ -0,0 +1,199 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"graphql-comments/internal/models"
+	"graphql-comments/internal/storage"
+)
+
+func TestAnalyzeQuery(t *testing.T) {
+	schema, err := BuildSchema(Config{Storage: storage.NewMemoryStorage()})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		want      QueryCost
+	}{
+		{
+			name:  "скалярные поля",
+			query: `{ post(id: "post_1") { id title } }`,
+			want:  QueryCost{Depth: 2, Complexity: 3},
+		},
+		{
+			name:  "список без размера",
+			query: `{ posts { id comments { id } } }`,
+			// posts: 1 + 10 * (id + comments: 1 + 10 * id)
+			want: QueryCost{Depth: 3, Complexity: 1 + 10*(1+1+10*1)},
+		},
+		{
+			name:      "размер из переменной",
+			query:     `query($n: Int) { comment(id: "c") { replies(first: $n) { id content } } }`,
+			variables: map[string]interface{}{"n": float64(3)},
+			want:      QueryCost{Depth: 3, Complexity: 1 + 1 + 3*2},
+		},
+		{
+			name:  "вложенные ответы не перемножаются",
+			query: `{ comment(id: "c") { replies { replies { replies { id } } } } }`,
+			// comment: 1 + replies: 1 + 10 * (replies: 1 + (replies: 1 + id))
+			want: QueryCost{Depth: 5, Complexity: 1 + 1 + 10*(1+(1+1))},
+		},
+		{
+			name:  "явный размер вложенных ответов учитывается",
+			query: `{ comment(id: "c") { replies { replies(first: 3) { id } } } }`,
+			want:  QueryCost{Depth: 4, Complexity: 1 + 1 + 10*(1+3*1)},
+		},
+		{
+			name:  "размер из значения по умолчанию",
+			query: `{ auditLog { id } }`,
+			want:  QueryCost{Depth: 2, Complexity: 1 + 50},
+		},
+		{
+			name:  "страница соединения задает число ребер",
+			query: `{ postsConnection(first: 5) { totalCount edges { node { id } } } }`,
+			want:  QueryCost{Depth: 4, Complexity: 1 + 1 + 1 + 5*(1+1)},
+		},
+		{
+			name:  "псевдонимы и фрагменты",
+			query: `{ a: post(id: "1") { ...F } b: post(id: "2") { ...F } } fragment F on Post { x: id title }`,
+			want:  QueryCost{Depth: 2, Complexity: 6, Aliases: 4},
+		},
+		{
+			name:  "интроспекция не учитывается",
+			query: `{ __schema { types { name } } post(id: "1") { __typename id } }`,
+			want:  QueryCost{Depth: 2, Complexity: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := AnalyzeQuery(schema, tt.query, "", tt.variables)
+			if !ok || got != tt.want {
+				t.Errorf("Ожидали %+v, получили %+v (%t)", tt.want, got, ok)
+			}
+		})
+	}
+
+	if _, ok := AnalyzeQuery(schema, `{ post(`, "", nil); ok {
+		t.Error("Ожидали отказ для неразобранного запроса")
+	}
+}
+
+func TestDefaultQueryLimits_DeepThread(t *testing.T) {
+	schema, err := BuildSchema(Config{Storage: storage.NewMemoryStorage()})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+
+	// Дерево комментариев поста с веткой ответов в десять уровней
+	fields := "id content author createdAt"
+	replies := fields
+	for i := 0; i < 10; i++ {
+		replies = fields + " replies { " + replies + " }"
+	}
+	for _, query := range []string{
+		`{ post(id: "post_1") { id title comments { ` + replies + ` } } }`,
+		`{ comment(id: "c") { ` + replies + ` } }`,
+		`{ posts { comments { replies { replies { id content author } } } } }`,
+	} {
+		cost, ok := AnalyzeQuery(schema, query, "", nil)
+		if !ok {
+			t.Fatalf("Запрос не разобран: %s", query)
+		}
+		if err := DefaultQueryLimits().Check(cost); err != nil {
+			t.Errorf("Ожидали, что запрос пройдет ограничения по умолчанию, получили %v (%+v)", err, cost)
+		}
+	}
+}
+
+func TestAnalyzeQuery_FragmentFanOut(t *testing.T) {
+	schema, err := BuildSchema(Config{Storage: storage.NewMemoryStorage()})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+
+	// Каждый фрагмент дважды разворачивает следующий: 2^40 полей без запоминания
+	var query strings.Builder
+	query.WriteString(`{ comment(id: "c") { ...F0 } }`)
+	for i := 0; i < 40; i++ {
+		query.WriteString(" fragment F" + strconv.Itoa(i) + " on Comment { ...F" + strconv.Itoa(i+1) + " ...F" + strconv.Itoa(i+1) + " }")
+	}
+	query.WriteString(" fragment F40 on Comment { id }")
+
+	start := time.Now()
+	cost, ok := AnalyzeQuery(schema, query.String(), "", nil)
+	if !ok || cost.Complexity != maxCost {
+		t.Errorf("Ожидали насыщенную стоимость, получили %+v (%t)", cost, ok)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Анализ занял %s", elapsed)
+	}
+}
+
+func TestHandler_QueryLimits(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	store.CreatePost(context.Background(), &models.Post{ID: "post_1", Title: "Пост", Content: "Текст"})
+	schema, err := BuildSchema(Config{Storage: store})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+	server := httptest.NewServer(NewHandler(schema, HandlerConfig{QueryLimits: QueryLimits{MaxDepth: 5, MaxAliases: 2}}))
+	defer server.Close()
+
+	post := func(query string) map[string]interface{} {
+		body, _ := json.Marshal(map[string]string{"query": query})
+		resp, err := http.Post(server.URL, "application/json", strings.NewReader(string(body)))
+		if err != nil {
+			t.Fatalf("Ошибка запроса: %v", err)
+		}
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("Ошибка разбора ответа: %v", err)
+		}
+		return result
+	}
+
+	// 1. Допустимый запрос выполняется, стоимость приходит в extensions
+	result := post(`{ post(id: "post_1") { title } }`)
+	if result["errors"] != nil || result["data"] == nil {
+		t.Fatalf("Ожидали успешный ответ, получили %v", result)
+	}
+	cost := result["extensions"].(map[string]interface{})["cost"].(map[string]interface{})
+	if cost["depth"] != float64(2) || cost["complexity"] != float64(2) {
+		t.Errorf("Неожиданная стоимость: %v", cost)
+	}
+
+	// 2. Глубокая ветка ответов отклоняется без выполнения
+	deep := `{ post(id: "post_1") { comments { replies { replies { replies { replies { id } } } } } } }`
+	result = post(deep)
+	if result["data"] != nil {
+		t.Errorf("Запрос сверх ограничения не должен выполняться, получили %v", result["data"])
+	}
+	errs, _ := result["errors"].([]interface{})
+	if len(errs) != 1 {
+		t.Fatalf("Ожидали одну ошибку, получили %v", result)
+	}
+	extensions := errs[0].(map[string]interface{})["extensions"].(map[string]interface{})
+	if extensions["code"] != CodeQueryTooComplex || extensions["limit"] != "depth" || extensions["value"] != float64(7) {
+		t.Errorf("Неожиданные extensions: %v", extensions)
+	}
+
+	// 3. Псевдонимы считаются отдельно
+	result = post(`{ a: post(id: "post_1") { id } b: post(id: "post_1") { id } c: post(id: "post_1") { id } }`)
+	errs, _ = result["errors"].([]interface{})
+	if len(errs) != 1 || errs[0].(map[string]interface{})["extensions"].(map[string]interface{})["limit"] != "aliases" {
+		t.Errorf("Ожидали отказ по псевдонимам, получили %v", result)
+	}
+}
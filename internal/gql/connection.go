@@ -0,0 +1,78 @@
+package gql
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"graphql-comments/internal/storage"
+
+	"github.com/graphql-go/graphql"
+)
+
+// Префиксы курсоров, чтобы курсор поста нельзя было передать в пагинацию комментариев
+const (
+	postCursorPrefix    = "post:"
+	commentCursorPrefix = "comment:"
+)
+
+// connectionArgs - аргументы пагинации Relay для полей-соединений
+func connectionArgs() graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{
+		"first":  &graphql.ArgumentConfig{Type: graphql.Int},
+		"after":  &graphql.ArgumentConfig{Type: graphql.String},
+		"last":   &graphql.ArgumentConfig{Type: graphql.Int},
+		"before": &graphql.ArgumentConfig{Type: graphql.String},
+	}
+}
+
+// encodeCursor кодирует ID элемента в непрозрачный курсор
+func encodeCursor(prefix, id string) string {
+	return base64.StdEncoding.EncodeToString([]byte(prefix + id))
+}
+
+// decodeCursor достает ID элемента из курсора
+func decodeCursor(prefix, cursor string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), prefix) {
+		return "", badUserInput("некорректный курсор")
+	}
+	return strings.TrimPrefix(string(raw), prefix), nil
+}
+
+// pageParamsFromArgs переводит аргументы first/after/last/before в storage.PageParams
+func pageParamsFromArgs(args map[string]interface{}, prefix string) (storage.PageParams, error) {
+	var page storage.PageParams
+
+	if first, ok := args["first"].(int); ok {
+		if first < 0 {
+			return page, badUserInput("first не может быть отрицательным")
+		}
+		page.First = &first
+	}
+	if last, ok := args["last"].(int); ok {
+		if last < 0 {
+			return page, badUserInput("last не может быть отрицательным")
+		}
+		page.Last = &last
+	}
+	if page.First != nil && page.Last != nil {
+		return page, badUserInput("нельзя одновременно указывать first и last")
+	}
+
+	if after, ok := args["after"].(string); ok {
+		id, err := decodeCursor(prefix, after)
+		if err != nil {
+			return page, err
+		}
+		page.After = &id
+	}
+	if before, ok := args["before"].(string); ok {
+		id, err := decodeCursor(prefix, before)
+		if err != nil {
+			return page, err
+		}
+		page.Before = &id
+	}
+
+	return page, nil
+}
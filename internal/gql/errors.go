@@ -0,0 +1,136 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"graphql-comments/internal/auth"
+	"graphql-comments/internal/policy"
+	"graphql-comments/internal/ratelimit"
+	"graphql-comments/internal/storage"
+
+	"github.com/graphql-go/graphql"
+)
+
+// Коды ошибок в extensions.code. Одинаковы для обоих хранилищ,
+// клиенты ветвятся по ним, а не по тексту сообщения
+const (
+	CodeNotFound         = "NOT_FOUND"
+	CodeAlreadyExists    = "ALREADY_EXISTS"
+	CodeParentNotFound   = "PARENT_NOT_FOUND"
+	CodeConflict         = "CONFLICT"
+	CodeCommentsDisabled = "COMMENTS_DISABLED"
+	CodeCommentDeleted   = "COMMENT_DELETED"
+	CodeBadUserInput     = "BAD_USER_INPUT"
+	CodeDeadlineExceeded = "DEADLINE_EXCEEDED"
+	CodeUnauthenticated  = auth.CodeUnauthenticated
+	CodeForbidden        = "FORBIDDEN"
+	CodeThreadLocked     = "THREAD_LOCKED"
+	CodeUserBanned       = "USER_BANNED"
+	CodeNotPending       = "NOT_PENDING"
+	CodeEditFlagged      = "EDIT_FLAGGED"
+	CodeRateLimited      = ratelimit.CodeRateLimited
+	CodeQueryTooComplex  = "QUERY_TOO_COMPLEX"
+)
+
+// storageErrorCodes сопоставляет ошибки хранилища и политики доступа кодам.
+// ErrParentNotFound проверяется раньше общего ErrNotFound
+var storageErrorCodes = []struct {
+	err  error
+	code string
+}{
+	{storage.ErrParentNotFound, CodeParentNotFound},
+	{storage.ErrNotFound, CodeNotFound},
+	{storage.ErrAlreadyExists, CodeAlreadyExists},
+	{storage.ErrConflict, CodeConflict},
+	{storage.ErrCommentsDisabled, CodeCommentsDisabled},
+	{storage.ErrCommentDeleted, CodeCommentDeleted},
+	{storage.ErrThreadLocked, CodeThreadLocked},
+	{storage.ErrUserBanned, CodeUserBanned},
+	{storage.ErrNotPending, CodeNotPending},
+	{storage.ErrEditFlagged, CodeEditFlagged},
+	{policy.ErrUnauthenticated, CodeUnauthenticated},
+	{policy.ErrForbidden, CodeForbidden},
+}
+
+// codedError - ошибка GraphQL с машиночитаемым кодом в extensions.code
+type codedError struct {
+	code    string
+	message string
+}
+
+func (e *codedError) Error() string {
+	return e.message
+}
+
+// Extensions реализует gqlerrors.ExtendedError
+func (e *codedError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+// badUserInput - ошибка в аргументах запроса
+func badUserInput(message string) error {
+	return &codedError{code: CodeBadUserInput, message: message}
+}
+
+// presentError приводит ошибки хранилища к виду, который видит клиент:
+// сообщение остается прежним, в extensions.code добавляется стабильный код
+func presentError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &codedError{code: CodeDeadlineExceeded, message: "превышено время выполнения операции"}
+	}
+	for _, mapping := range storageErrorCodes {
+		if errors.Is(err, mapping.err) {
+			return &codedError{code: mapping.code, message: err.Error()}
+		}
+	}
+	return err
+}
+
+// wrapResolvers оборачивает резолверы всех полей схемы: ошибки проходят
+// через presentError (в том числе из отложенных резолверов),
+// а хранилище всегда получает непустой контекст
+func wrapResolvers(schema *graphql.Schema) {
+	for name, namedType := range schema.TypeMap() {
+		object, ok := namedType.(*graphql.Object)
+		// Служебные типы интроспекции не трогаем
+		if !ok || strings.HasPrefix(name, "__") {
+			continue
+		}
+
+		for _, field := range object.Fields() {
+			if field.Resolve != nil {
+				field.Resolve = wrapResolve(field.Resolve)
+			}
+		}
+	}
+}
+
+// wrapResolve оборачивает один резолвер
+func wrapResolve(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		// graphql.Do без Context передает резолверам nil
+		if p.Context == nil {
+			p.Context = context.Background()
+		}
+
+		result, err := resolve(p)
+		if err != nil {
+			return result, presentError(err)
+		}
+
+		// Отложенный резолвер (загрузчик) вернет ошибку позже
+		if thunk, ok := result.(func() (interface{}, error)); ok {
+			return func() (interface{}, error) {
+				value, err := thunk()
+				if err != nil {
+					return value, presentError(err)
+				}
+				return value, nil
+			}, nil
+		}
+
+		return result, nil
+	}
+}
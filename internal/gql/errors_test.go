@@ -0,0 +1,97 @@
+package gql
+
+import (
+	"context"
+	"graphql-comments/internal/models"
+	"graphql-comments/internal/ratelimit"
+	"graphql-comments/internal/storage"
+	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
+)
+
+// slowStorage отвечает на запрос постов дольше любого разумного таймаута
+type slowStorage struct {
+	storage.Storage
+}
+
+func (s *slowStorage) GetAllPosts(ctx context.Context) ([]*models.Post, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestStorageTimeout_DeadlineExceededCode(t *testing.T) {
+	store := storage.WithTimeouts(&slowStorage{Storage: storage.NewMemoryStorage()},
+		storage.Timeouts{Read: 10 * time.Millisecond})
+
+	schema, err := BuildSchema(Config{Storage: store})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+
+	result := graphql.Do(graphql.Params{Schema: *schema, RequestString: `{ posts { id } }`})
+	if len(result.Errors) != 1 {
+		t.Fatalf("Ожидали одну ошибку, получили %v", result.Errors)
+	}
+	if code := result.Errors[0].Extensions["code"]; code != CodeDeadlineExceeded {
+		t.Errorf("Ожидали код %s, получили %v", CodeDeadlineExceeded, code)
+	}
+}
+
+func TestPresentError_StorageCodes(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Текст"})
+
+	schema, err := BuildSchema(Config{Storage: store})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+
+	tests := []struct {
+		query string
+		code  string
+	}{
+		{`{ post(id: "missing") { id } }`, CodeNotFound},
+		{`mutation { createComment(input: {postId: "post_1", parentId: "missing", content: "Ответ"}) { id } }`, CodeParentNotFound},
+		{`{ postsConnection(first: -1) { totalCount } }`, CodeBadUserInput},
+	}
+	for _, tt := range tests {
+		result := graphql.Do(graphql.Params{Schema: *schema, RequestString: tt.query})
+		if len(result.Errors) != 1 {
+			t.Errorf("%s: ожидали одну ошибку, получили %v", tt.query, result.Errors)
+			continue
+		}
+		if code := result.Errors[0].Extensions["code"]; code != tt.code {
+			t.Errorf("%s: ожидали код %s, получили %v", tt.query, tt.code, code)
+		}
+	}
+}
+
+func TestCreateComment_RateLimitedCode(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Текст"})
+
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(), ratelimit.Limits{
+		Comments: ratelimit.Limit{Burst: 1, Interval: time.Minute},
+	})
+	schema, err := BuildSchema(Config{Storage: store, RateLimiter: limiter})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+
+	query := `mutation { createComment(input: {postId: "post_1", content: "Комментарий"}) { id } }`
+	client := ratelimit.WithClientIP(ctx, "10.0.0.1")
+	if result := graphql.Do(graphql.Params{Schema: *schema, RequestString: query, Context: client}); len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+	result := graphql.Do(graphql.Params{Schema: *schema, RequestString: query, Context: client})
+	if len(result.Errors) != 1 {
+		t.Fatalf("Ожидали одну ошибку, получили %v", result.Errors)
+	}
+	if extensions := result.Errors[0].Extensions; extensions["code"] != CodeRateLimited || extensions["retryAfter"] != 60 {
+		t.Errorf("Ожидали %s с retryAfter 60, получили %v", CodeRateLimited, extensions)
+	}
+}
@@ -1,17 +1,112 @@
 package gql
 
 import (
+	"encoding/json"
 	"net/http"
+	"strings"
 
+	"graphql-comments/internal/persisted"
+
+	"github.com/gorilla/websocket"
 	"github.com/graphql-go/graphql"
 	"github.com/graphql-go/handler"
 )
 
+// HandlerConfig - настройки обработчика GraphQL запросов
+type HandlerConfig struct {
+	// QueryLimits - ограничения глубины, стоимости и числа псевдонимов запроса
+	// (нулевые поля - без ограничения)
+	QueryLimits QueryLimits
+	// PersistedQueries - APQ или список разрешенных операций (nil - только тексты запросов)
+	PersistedQueries *persisted.Queries
+	// AllowedOrigins - источники (https://app.example.com), с чьих страниц можно открыть
+	// WebSocket. Страницы с адреса самого сервера и клиенты без Origin допускаются всегда
+	AllowedOrigins []string
+}
 
-func NewHandler(schema *graphql.Schema) http.Handler {
-	return handler.New(&handler.Config{
+// NewHandler создает обработчик GraphQL: разбор запроса, подстановка persisted запроса
+// по хэшу, проверка стоимости и выполнение. Библиотечному обработчику оставлена
+// только пустая страница GraphiQL (запросы со страницы приходят отдельными POST),
+// WebSocket подписки обслуживаются на том же пути
+func NewHandler(schema *graphql.Schema, cfg HandlerConfig) http.Handler {
+	graphiQLHandler := handler.New(&handler.Config{
 		Schema:   schema,
 		GraphiQL: true,
 		Pretty:   true,
 	})
+	wsHandler := NewWebSocketHandler(schema, cfg)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if websocket.IsWebSocketUpgrade(r) {
+			wsHandler.ServeHTTP(w, r)
+			return
+		}
+
+		graphiQL := wantsGraphiQL(r)
+		req, err := parseRequest(w, r)
+		if err != nil {
+			writeResult(w, http.StatusBadRequest, errorResult(badUserInput(err.Error())))
+			return
+		}
+		if graphiQL && req.Query == "" && req.Extensions.PersistedQuery == nil {
+			serveGraphiQL(graphiQLHandler, w, r)
+			return
+		}
+
+		// Каждый запрос получает свои загрузчики с батчингом
+		ctx := WithLoaders(r.Context())
+		query, err := cfg.PersistedQueries.Resolve(ctx, req.Query, req.Extensions.PersistedQuery)
+		if err != nil {
+			writeResult(w, http.StatusOK, errorResult(err))
+			return
+		}
+		params := graphql.Params{
+			Schema:         *schema,
+			RequestString:  query,
+			VariableValues: req.Variables,
+			OperationName:  req.OperationName,
+			Context:        ctx,
+		}
+
+		// Стоимость проверяется до выполнения
+		cost, result := checkQuery(params, cfg.QueryLimits)
+		if result != nil {
+			writeResult(w, http.StatusOK, result)
+			return
+		}
+		result = withCost(graphql.Do(params), cost)
+		// Выполненный запрос прошел разбор и валидацию - его можно запомнить по хэшу
+		if result.Data != nil {
+			cfg.PersistedQueries.Register(ctx, req.Query, req.Extensions.PersistedQuery)
+		}
+		writeResult(w, http.StatusOK, result)
+	})
+}
+
+// serveGraphiQL отдает пустую страницу GraphiQL. Библиотечный обработчик сам читает
+// запрос из адреса и тела, поэтому получает копию запроса без них: иначе он выполнил бы
+// текст в обход списка разрешенных операций и ограничений стоимости
+func serveGraphiQL(graphiQLHandler http.Handler, w http.ResponseWriter, r *http.Request) {
+	page := r.Clone(r.Context())
+	page.Method = http.MethodGet
+	page.URL.RawQuery = ""
+	page.Body = http.NoBody
+	page.ContentLength = 0
+	graphiQLHandler.ServeHTTP(w, page)
+}
+
+// writeResult отправляет ответ GraphQL в JSON
+func writeResult(w http.ResponseWriter, status int, result *graphql.Result) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	buff, _ := json.MarshalIndent(result, "", "\t")
+	w.Write(buff)
+}
+
+// wantsGraphiQL сообщает, что запрос пришел из браузера и ждет страницу GraphiQL
+// (так же решает библиотечный обработчик)
+func wantsGraphiQL(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	_, raw := r.URL.Query()["raw"]
+	return !raw && !strings.Contains(accept, "application/json") && strings.Contains(accept, "text/html")
 }
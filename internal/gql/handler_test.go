@@ -0,0 +1,117 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"graphql-comments/internal/models"
+	"graphql-comments/internal/persisted"
+	"graphql-comments/internal/storage"
+)
+
+func TestHandler_PersistedQueries(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	store.CreatePost(context.Background(), &models.Post{ID: "post_1", Title: "Пост", Content: "Текст"})
+	schema, err := BuildSchema(Config{Storage: store})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+	server := httptest.NewServer(NewHandler(schema, HandlerConfig{
+		PersistedQueries: persisted.NewAPQ(persisted.NewLRUStore(10, nil), nil),
+	}))
+	defer server.Close()
+
+	decode := func(resp *http.Response, err error) map[string]interface{} {
+		if err != nil {
+			t.Fatalf("Ошибка запроса: %v", err)
+		}
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("Ошибка разбора ответа: %v", err)
+		}
+		return result
+	}
+	errorCode := func(result map[string]interface{}) interface{} {
+		errs, _ := result["errors"].([]interface{})
+		if len(errs) != 1 {
+			return nil
+		}
+		return errs[0].(map[string]interface{})["extensions"].(map[string]interface{})["code"]
+	}
+
+	query := `{ post(id: "post_1") { title } }`
+	extensions := `{"persistedQuery": {"version": 1, "sha256Hash": "` + persisted.Hash(query) + `"}}`
+	byHash := server.URL + "?" + url.Values{"extensions": {extensions}}.Encode()
+
+	// 1. GET по неизвестному хэшу - PERSISTED_QUERY_NOT_FOUND
+	if result := decode(http.Get(byHash)); errorCode(result) != persisted.CodeNotFound {
+		t.Fatalf("Ожидали %s, получили %v", persisted.CodeNotFound, result)
+	}
+
+	// 2. POST с текстом и хэшем регистрирует запрос
+	body, _ := json.Marshal(map[string]interface{}{"query": query, "extensions": json.RawMessage(extensions)})
+	if result := decode(http.Post(server.URL, "application/json", strings.NewReader(string(body)))); result["errors"] != nil {
+		t.Fatalf("Ошибки выполнения запроса: %v", result["errors"])
+	}
+
+	// 3. Повторный GET по хэшу выполняет зарегистрированный запрос
+	result := decode(http.Get(byHash))
+	post, _ := result["data"].(map[string]interface{})["post"].(map[string]interface{})
+	if post["title"] != "Пост" {
+		t.Errorf("Ожидали пост по хэшу, получили %v", result)
+	}
+
+	// 4. Обычные запросы без хэша работают как раньше, в том числе application/graphql
+	result = decode(http.Post(server.URL, "application/graphql", strings.NewReader(`{ posts { id } }`)))
+	if result["errors"] != nil || len(result["data"].(map[string]interface{})["posts"].([]interface{})) != 1 {
+		t.Errorf("Ожидали список постов, получили %v", result)
+	}
+
+	// 5. Неразбираемое тело - 400
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(`{"query":`))
+	if err != nil {
+		t.Fatalf("Ошибка запроса: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Ожидали 400, получили %d", resp.StatusCode)
+	}
+}
+
+func TestHandler_GraphiQLDoesNotExecuteURLQuery(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "p1", Title: "Пост", Content: "Текст"})
+	schema, err := BuildSchema(Config{Storage: store})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+	allowed := `{ posts { id } }`
+	server := httptest.NewServer(NewHandler(schema, HandlerConfig{
+		PersistedQueries: persisted.NewAllowlist(map[string]string{persisted.Hash(allowed): allowed}),
+	}))
+	defer server.Close()
+
+	// Текст в адресе расходится с телом: выполняться может только проверенное тело
+	target := server.URL + "?" + url.Values{"query": {`mutation { deletePost(id: "p1") }`}}.Encode()
+	for _, body := range []string{`{"query": "{ posts { id } }"}`, `{}`} {
+		req, _ := http.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "text/html")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка запроса: %v", err)
+		}
+		resp.Body.Close()
+
+		if _, err := store.GetPost(ctx, "p1"); err != nil {
+			t.Fatalf("Пост удален запросом из адреса (тело %s): %v", body, err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package gql
+
+import (
+	"context"
+	"sync"
+
+	"graphql-comments/internal/models"
+	"graphql-comments/internal/storage"
+)
+
+// loadersKey - ключ контекста, под которым хранятся загрузчики запроса
+type loadersKey struct{}
+
+// Loaders - загрузчики с батчингом, живущие в рамках одного GraphQL запроса
+type Loaders struct {
+	mu             sync.Mutex
+	comments       *batchLoader[[]*models.Comment]
+	reactionCounts *batchLoader[[]*models.ReactionCount]
+	reactions      map[string]*batchLoader[*models.Reaction]
+}
+
+// WithLoaders добавляет в контекст новый набор загрузчиков.
+// Вызывается один раз на запрос, чтобы кэш не переживал запрос
+func WithLoaders(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loadersKey{}, &Loaders{})
+}
+
+// loadersFromContext возвращает загрузчики запроса или nil, если они к контексту не подключены
+func loadersFromContext(ctx context.Context) *Loaders {
+	if ctx == nil {
+		return nil
+	}
+	loaders, _ := ctx.Value(loadersKey{}).(*Loaders)
+	return loaders
+}
+
+// commentLoaderFromContext возвращает загрузчик комментариев постов запроса
+// или nil, если загрузчики к контексту не подключены
+func commentLoaderFromContext(ctx context.Context, store storage.Storage) *batchLoader[[]*models.Comment] {
+	loaders := loadersFromContext(ctx)
+	if loaders == nil {
+		return nil
+	}
+
+	loaders.mu.Lock()
+	defer loaders.mu.Unlock()
+
+	if loaders.comments == nil {
+		loaders.comments = newBatchLoader(ctx, store.GetCommentsByPostIDs)
+	}
+	return loaders.comments
+}
+
+// reactionCountsLoaderFromContext возвращает загрузчик числа реакций на комментарии
+// или nil, если загрузчики к контексту не подключены
+func reactionCountsLoaderFromContext(ctx context.Context, store storage.Storage) *batchLoader[[]*models.ReactionCount] {
+	loaders := loadersFromContext(ctx)
+	if loaders == nil {
+		return nil
+	}
+
+	loaders.mu.Lock()
+	defer loaders.mu.Unlock()
+
+	if loaders.reactionCounts == nil {
+		loaders.reactionCounts = newBatchLoader(ctx, store.GetReactionCountsByCommentIDs)
+	}
+	return loaders.reactionCounts
+}
+
+// reactionLoaderFromContext возвращает загрузчик реакций пользователя на комментарии
+// или nil, если загрузчики к контексту не подключены
+func reactionLoaderFromContext(ctx context.Context, store storage.Storage, userID string) *batchLoader[*models.Reaction] {
+	loaders := loadersFromContext(ctx)
+	if loaders == nil {
+		return nil
+	}
+
+	loaders.mu.Lock()
+	defer loaders.mu.Unlock()
+
+	// Без аутентификации пользователь задается аргументом поля и может отличаться
+	// от комментария к комментарию, поэтому загрузчик у каждого пользователя свой
+	if loaders.reactions == nil {
+		loaders.reactions = make(map[string]*batchLoader[*models.Reaction])
+	}
+	loader, exists := loaders.reactions[userID]
+	if !exists {
+		loader = newBatchLoader(ctx, func(ctx context.Context, commentIDs []string) (map[string]*models.Reaction, error) {
+			return store.GetReactionsByCommentIDs(ctx, commentIDs, userID)
+		})
+		loaders.reactions[userID] = loader
+	}
+	return loader
+}
+
+// batchLoader собирает ключи и загружает значения по ним одним запросом.
+// graphql-go вычисляет отложенные резолверы (thunks) в ширину,
+// поэтому к первому вызову все ключи текущего уровня уже поставлены в очередь
+type batchLoader[V any] struct {
+	ctx   context.Context
+	fetch func(ctx context.Context, keys []string) (map[string]V, error)
+
+	mu      sync.Mutex
+	pending []string
+	entries map[string]*batchLoadEntry[V]
+}
+
+// batchLoadEntry - результат загрузки по одному ключу
+type batchLoadEntry[V any] struct {
+	done  bool
+	value V
+	err   error
+}
+
+// newBatchLoader создает загрузчик, который получает значения функцией fetch
+func newBatchLoader[V any](ctx context.Context, fetch func(ctx context.Context, keys []string) (map[string]V, error)) *batchLoader[V] {
+	return &batchLoader[V]{
+		ctx:     ctx,
+		fetch:   fetch,
+		entries: make(map[string]*batchLoadEntry[V]),
+	}
+}
+
+// Load ставит ключ в очередь и возвращает функцию, которая вернет значение по нему.
+// Для ключа, которого нет в ответе fetch, возвращается нулевое значение
+func (l *batchLoader[V]) Load(key string) func() (V, error) {
+	l.mu.Lock()
+	if _, exists := l.entries[key]; !exists {
+		l.entries[key] = &batchLoadEntry[V]{}
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		entry := l.entries[key]
+		if !entry.done {
+			l.dispatch()
+		}
+		return entry.value, entry.err
+	}
+}
+
+// dispatch загружает значения всех ключей из очереди. Вызывается под l.mu
+func (l *batchLoader[V]) dispatch() {
+	keys := l.pending
+	l.pending = nil
+
+	values, err := l.fetch(l.ctx, keys)
+	for _, key := range keys {
+		entry := l.entries[key]
+		entry.done = true
+		entry.value = values[key]
+		entry.err = err
+	}
+}
@@ -0,0 +1,149 @@
+package gql
+
+import (
+	"context"
+	"graphql-comments/internal/models"
+	"graphql-comments/internal/storage"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+)
+
+// countingStorage считает обращения к хранилищу за комментариями
+type countingStorage struct {
+	storage.Storage
+	singleCalls int
+	batchCalls  int
+
+	reactionSingleCalls int
+	reactionBatchCalls  int
+}
+
+func (s *countingStorage) GetCommentsByPostID(ctx context.Context, postID string) ([]*models.Comment, error) {
+	s.singleCalls++
+	return s.Storage.GetCommentsByPostID(ctx, postID)
+}
+
+func (s *countingStorage) GetCommentsByPostIDs(ctx context.Context, postIDs []string) (map[string][]*models.Comment, error) {
+	s.batchCalls++
+	return s.Storage.GetCommentsByPostIDs(ctx, postIDs)
+}
+
+func (s *countingStorage) GetReactionCounts(ctx context.Context, commentID string) ([]*models.ReactionCount, error) {
+	s.reactionSingleCalls++
+	return s.Storage.GetReactionCounts(ctx, commentID)
+}
+
+func (s *countingStorage) GetReactionCountsByCommentIDs(ctx context.Context, commentIDs []string) (map[string][]*models.ReactionCount, error) {
+	s.reactionBatchCalls++
+	return s.Storage.GetReactionCountsByCommentIDs(ctx, commentIDs)
+}
+
+func (s *countingStorage) GetReaction(ctx context.Context, commentID, userID string) (*models.Reaction, error) {
+	s.reactionSingleCalls++
+	return s.Storage.GetReaction(ctx, commentID, userID)
+}
+
+func (s *countingStorage) GetReactionsByCommentIDs(ctx context.Context, commentIDs []string, userID string) (map[string]*models.Reaction, error) {
+	s.reactionBatchCalls++
+	return s.Storage.GetReactionsByCommentIDs(ctx, commentIDs, userID)
+}
+
+func TestCommentLoader_BatchesPosts(t *testing.T) {
+	memory := storage.NewMemoryStorage()
+	ctx := context.Background()
+	for _, id := range []string{"post_1", "post_2", "post_3"} {
+		memory.CreatePost(ctx, &models.Post{ID: id, Title: "Пост", Content: "Контент"})
+		memory.CreateComment(ctx, &models.Comment{ID: "comment_" + id, PostID: id, Content: "Текст"})
+	}
+	store := &countingStorage{Storage: memory}
+
+	schema, err := BuildSchema(Config{Storage: store})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+
+	// Запрос с загрузчиками в контексте, как в HTTP обработчике
+	result := graphql.Do(graphql.Params{
+		Schema:        *schema,
+		RequestString: `{ posts { id comments { id content } } }`,
+		Context:       WithLoaders(context.Background()),
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+
+	// Комментарии трех постов загружены одним батчем
+	if store.batchCalls != 1 || store.singleCalls != 0 {
+		t.Errorf("Ожидали 1 батч и 0 одиночных запросов, получили %d и %d", store.batchCalls, store.singleCalls)
+	}
+
+	for _, post := range result.Data.(map[string]interface{})["posts"].([]interface{}) {
+		comments := post.(map[string]interface{})["comments"].([]interface{})
+		if len(comments) != 1 {
+			t.Errorf("Ожидали 1 комментарий у поста, получили %d", len(comments))
+		}
+	}
+}
+
+func TestReactionLoaders_BatchComments(t *testing.T) {
+	memory := storage.NewMemoryStorage()
+	ctx := context.Background()
+	memory.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Контент"})
+	fire := "🔥"
+	for _, id := range []string{"comment_1", "comment_2", "comment_3"} {
+		memory.CreateComment(ctx, &models.Comment{ID: id, PostID: "post_1", Content: "Текст"})
+	}
+	memory.ReactToComment(ctx, "comment_1", "alice", &fire)
+	store := &countingStorage{Storage: memory}
+
+	schema, err := BuildSchema(Config{Storage: store})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema: *schema,
+		RequestString: `{ post(id: "post_1") { comments {
+			id reactionCounts { emoji count } viewerReaction(user: "alice") { emoji }
+		} } }`,
+		Context: WithLoaders(context.Background()),
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+
+	// Счетчики и реакции alice на три комментария - по одному батчу
+	if store.reactionBatchCalls != 2 || store.reactionSingleCalls != 0 {
+		t.Errorf("Ожидали 2 батча и 0 одиночных запросов, получили %d и %d", store.reactionBatchCalls, store.reactionSingleCalls)
+	}
+
+	for _, item := range result.Data.(map[string]interface{})["post"].(map[string]interface{})["comments"].([]interface{}) {
+		comment := item.(map[string]interface{})
+		counts := comment["reactionCounts"].([]interface{})
+		emoji := comment["viewerReaction"].(map[string]interface{})["emoji"]
+		if comment["id"] == "comment_1" {
+			if len(counts) != 1 || emoji != fire {
+				t.Errorf("Ожидали реакцию %s у comment_1, получили %v и %v", fire, counts, emoji)
+			}
+		} else if len(counts) != 0 || emoji != nil {
+			t.Errorf("Ожидали пустые реакции у %v, получили %v и %v", comment["id"], counts, emoji)
+		}
+	}
+}
+
+func TestViewerReaction_NoUserArgWithAuth(t *testing.T) {
+	schema, err := BuildSchema(Config{Storage: storage.NewMemoryStorage(), RequireAuth: true})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+
+	// С аутентификацией чужие реакции через аргумент user не запросить
+	result := graphql.Do(graphql.Params{
+		Schema:        *schema,
+		RequestString: `{ comment(id: "comment_1") { viewerReaction(user: "alice") { emoji } } }`,
+	})
+	if len(result.Errors) == 0 {
+		t.Error("Ожидали ошибку валидации аргумента user")
+	}
+}
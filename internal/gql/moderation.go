@@ -0,0 +1,217 @@
+package gql
+
+import (
+	"context"
+	"fmt"
+
+	"graphql-comments/internal/auth"
+	"graphql-comments/internal/models"
+	"graphql-comments/internal/policy"
+
+	"github.com/graphql-go/graphql"
+)
+
+// maxAuditLogLimit - наибольшее число записей журнала в одном запросе
+const maxAuditLogLimit = 500
+
+// maxModerationQueueLimit - наибольшее число комментариев очереди модерации в одном запросе
+const maxModerationQueueLimit = 200
+
+// CommentStatusEnum - состояние комментария в очереди модерации
+var CommentStatusEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "CommentStatus",
+	Values: graphql.EnumValueConfigMap{
+		"PUBLISHED": &graphql.EnumValueConfig{Value: models.CommentPublished},
+		"PENDING":   &graphql.EnumValueConfig{Value: models.CommentPending},
+		"REJECTED":  &graphql.EnumValueConfig{Value: models.CommentRejected},
+	},
+})
+
+// HideCommentResolver скрывает комментарий или показывает его снова (только модераторы)
+func (r *ResolverContext) HideCommentResolver(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+	hidden, _ := p.Args["hidden"].(bool)
+
+	viewer, err := r.authorize(p.Context, policy.HideComment, "")
+	if err != nil {
+		return nil, err
+	}
+
+	action := "hideComment"
+	if !hidden {
+		action = "unhideComment"
+	}
+	reason, _ := p.Args["reason"].(string)
+	var comment *models.Comment
+	err = r.audited(p.Context, viewer, action, id, reason, func(ctx context.Context) (err error) {
+		comment, err = r.Storage.HideComment(ctx, id, hidden)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	comment.Replies = nil
+	return comment, nil
+}
+
+// LockThreadResolver закрывает ветку комментария для новых ответов или открывает ее (только модераторы)
+func (r *ResolverContext) LockThreadResolver(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+	locked, _ := p.Args["locked"].(bool)
+
+	viewer, err := r.authorize(p.Context, policy.LockThread, "")
+	if err != nil {
+		return nil, err
+	}
+
+	action := "lockThread"
+	if !locked {
+		action = "unlockThread"
+	}
+	reason, _ := p.Args["reason"].(string)
+	var comment *models.Comment
+	err = r.audited(p.Context, viewer, action, id, reason, func(ctx context.Context) (err error) {
+		comment, err = r.Storage.LockThread(ctx, id, locked)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	comment.Replies = nil
+	return comment, nil
+}
+
+// BanUserFromPostResolver запрещает пользователю комментировать пост
+// или снимает запрет (только модераторы)
+func (r *ResolverContext) BanUserFromPostResolver(p graphql.ResolveParams) (interface{}, error) {
+	postID, _ := p.Args["postId"].(string)
+	userID, _ := p.Args["userId"].(string)
+	reason, _ := p.Args["reason"].(string)
+	banned, _ := p.Args["banned"].(bool)
+
+	if err := r.Limits.ValidateAuthor(userID, "userId"); err != nil {
+		return false, err
+	}
+
+	viewer, err := r.authorize(p.Context, policy.BanUserFromPost, "")
+	if err != nil {
+		return false, err
+	}
+
+	action := "banUserFromPost"
+	if !banned {
+		action = "unbanUserFromPost"
+	}
+	details := fmt.Sprintf("post=%s", postID)
+	if reason != "" {
+		details += "; " + reason
+	}
+	err = r.audited(p.Context, viewer, action, userID, details, func(ctx context.Context) error {
+		if banned {
+			ban := &models.PostBan{PostID: postID, UserID: userID, Reason: reason, BannedBy: viewer.ID}
+			return r.Storage.BanUserFromPost(ctx, ban)
+		}
+		return r.Storage.UnbanUserFromPost(ctx, postID, userID)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// AuditLogResolver возвращает последние записи журнала модерации (только администраторы)
+func (r *ResolverContext) AuditLogResolver(p graphql.ResolveParams) (interface{}, error) {
+	limit, _ := p.Args["limit"].(int)
+	if limit < 1 || limit > maxAuditLogLimit {
+		return nil, badUserInput(fmt.Sprintf("limit должен быть от 1 до %d", maxAuditLogLimit))
+	}
+
+	if _, err := r.authorize(p.Context, policy.ReadAuditLog, ""); err != nil {
+		return nil, err
+	}
+
+	return r.Storage.GetAuditLog(p.Context, limit)
+}
+
+// ModerationQueueResolver возвращает комментарии, ожидающие проверки (только модераторы)
+func (r *ResolverContext) ModerationQueueResolver(p graphql.ResolveParams) (interface{}, error) {
+	postID, _ := p.Args["postId"].(string)
+	first, _ := p.Args["first"].(int)
+	if first < 1 || first > maxModerationQueueLimit {
+		return nil, badUserInput(fmt.Sprintf("first должен быть от 1 до %d", maxModerationQueueLimit))
+	}
+
+	if _, err := r.authorize(p.Context, policy.ModerateComments, ""); err != nil {
+		return nil, err
+	}
+
+	return r.Storage.GetModerationQueue(p.Context, postID, first)
+}
+
+// ApproveCommentResolver публикует комментарий из очереди модерации
+func (r *ResolverContext) ApproveCommentResolver(p graphql.ResolveParams) (interface{}, error) {
+	comment, err := r.setCommentStatus(p, models.CommentPublished, "approveComment")
+	if err != nil {
+		return nil, err
+	}
+
+	// Для подписчиков одобренный комментарий - новый, копия - как в CreateCommentResolver.
+	// Одобрить можно только комментарий на проверке, а опубликованный на проверку
+	// не возвращается (отмеченная правка отклоняется с ErrEditFlagged), поэтому
+	// одобрение - всегда первая публикация и подписчики не получат комментарий дважды
+	if r.Hub != nil {
+		published := *comment
+		r.Hub.Publish(&published)
+	}
+	return comment, nil
+}
+
+// RejectCommentResolver отклоняет комментарий из очереди модерации, он остается скрытым
+func (r *ResolverContext) RejectCommentResolver(p graphql.ResolveParams) (interface{}, error) {
+	return r.setCommentStatus(p, models.CommentRejected, "rejectComment")
+}
+
+// setCommentStatus проверяет права модератора, меняет статус комментария и пишет журнал
+func (r *ResolverContext) setCommentStatus(p graphql.ResolveParams, status models.CommentStatus, action string) (*models.Comment, error) {
+	id, _ := p.Args["id"].(string)
+
+	viewer, err := r.authorize(p.Context, policy.ModerateComments, "")
+	if err != nil {
+		return nil, err
+	}
+
+	reason, _ := p.Args["reason"].(string)
+	var comment *models.Comment
+	err = r.audited(p.Context, viewer, action, id, reason, func(ctx context.Context) (err error) {
+		comment, err = r.Storage.SetCommentStatus(ctx, id, status)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	comment.Replies = nil
+	return comment, nil
+}
+
+// canSeeComment сообщает, что пользователь запроса может видеть комментарий:
+// неопубликованные видят только модераторы и автор
+func canSeeComment(ctx context.Context, comment *models.Comment) bool {
+	if comment.Status == models.CommentPublished {
+		return true
+	}
+	viewer := auth.ViewerFromContext(ctx)
+	return viewer.HasRole(auth.RoleModerator) || (viewer != nil && viewer.ID == comment.Author)
+}
+
+// ModerationReasonResolver возвращает причину отправки на модерацию только модераторам
+func (r *ResolverContext) ModerationReasonResolver(p graphql.ResolveParams) (interface{}, error) {
+	comment, ok := p.Source.(*models.Comment)
+	if !ok || comment.ModerationReason == "" || !auth.ViewerFromContext(p.Context).HasRole(auth.RoleModerator) {
+		return nil, nil
+	}
+	return comment.ModerationReason, nil
+}
@@ -0,0 +1,192 @@
+package gql
+
+import (
+	"context"
+	"testing"
+
+	"graphql-comments/internal/auth"
+	"graphql-comments/internal/models"
+	"graphql-comments/internal/moderation"
+	"graphql-comments/internal/pubsub"
+	"graphql-comments/internal/storage"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestModerationQueue(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Контент"})
+
+	schema, err := BuildSchema(Config{
+		Storage:        store,
+		ContentFilters: []moderation.ContentFilter{moderation.NewBannedWords([]string{"казино"}), moderation.LinkLimit{Max: 1}},
+	})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+	as := func(viewer *auth.Viewer, request string) *graphql.Result {
+		return graphql.Do(graphql.Params{Schema: *schema, RequestString: request, Context: auth.WithViewer(context.Background(), viewer)})
+	}
+	alice := &auth.Viewer{ID: "alice", Role: auth.RoleUser}
+	bob := &auth.Viewer{ID: "bob", Role: auth.RoleUser}
+	moder := &auth.Viewer{ID: "moder", Role: auth.RoleModerator}
+
+	// 1. Чистый комментарий публикуется сразу, отмеченный уходит в очередь
+	result := as(alice, `mutation { createComment(input: {postId: "post_1", content: "Хорошая статья"}) { status } }`)
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+	if status := result.Data.(map[string]interface{})["createComment"].(map[string]interface{})["status"]; status != "PUBLISHED" {
+		t.Errorf("Ожидали PUBLISHED, получили %v", status)
+	}
+	result = as(alice, `mutation { createComment(input: {postId: "post_1", content: "Лучшее казино"}) { id status } }`)
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+	pending := result.Data.(map[string]interface{})["createComment"].(map[string]interface{})
+	if pending["status"] != "PENDING" {
+		t.Errorf("Ожидали PENDING, получили %v", pending["status"])
+	}
+	pendingID := pending["id"].(string)
+
+	// 2. Комментарий на проверке не виден в посте и другим пользователям, но виден автору
+	result = as(bob, `{ post(id: "post_1") { comments { id } } }`)
+	if comments := result.Data.(map[string]interface{})["post"].(map[string]interface{})["comments"].([]interface{}); len(comments) != 1 {
+		t.Errorf("Ожидали один опубликованный комментарий, получили %v", comments)
+	}
+	result = as(bob, `{ comment(id: "`+pendingID+`") { id } }`)
+	if len(result.Errors) == 0 || result.Errors[0].Extensions["code"] != CodeNotFound {
+		t.Errorf("Ожидали NOT_FOUND для чужого комментария на проверке, получили %v", result.Errors)
+	}
+	result = as(alice, `{ comment(id: "`+pendingID+`") { status moderationReason } }`)
+	own := result.Data.(map[string]interface{})["comment"].(map[string]interface{})
+	if own["status"] != "PENDING" || own["moderationReason"] != nil {
+		t.Errorf("Автор видит свой комментарий без причины модерации, получили %v", own)
+	}
+
+	// 3. Очередь доступна только модераторам и показывает причину
+	result = as(bob, `{ moderationQueue { id } }`)
+	if len(result.Errors) == 0 || result.Errors[0].Extensions["code"] != CodeForbidden {
+		t.Errorf("Ожидали FORBIDDEN, получили %v", result.Errors)
+	}
+	result = as(moder, `{ moderationQueue(postId: "post_1") { id moderationReason } }`)
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+	queue := result.Data.(map[string]interface{})["moderationQueue"].([]interface{})
+	if len(queue) != 1 || queue[0].(map[string]interface{})["moderationReason"] != `bannedWords: запрещенное слово "казино"` {
+		t.Errorf("Неожиданная очередь: %v", queue)
+	}
+
+	// 4. Одобренный комментарий появляется в посте, повторная модерация - NOT_PENDING
+	result = as(moder, `mutation { approveComment(id: "`+pendingID+`") { status } }`)
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+	result = as(bob, `{ post(id: "post_1") { comments { id } } }`)
+	if comments := result.Data.(map[string]interface{})["post"].(map[string]interface{})["comments"].([]interface{}); len(comments) != 2 {
+		t.Errorf("Ожидали два комментария после одобрения, получили %v", comments)
+	}
+	result = as(moder, `mutation { rejectComment(id: "`+pendingID+`") { id } }`)
+	if len(result.Errors) == 0 || result.Errors[0].Extensions["code"] != CodeNotPending {
+		t.Errorf("Ожидали NOT_PENDING, получили %v", result.Errors)
+	}
+
+	// 5. Отклонение пишется в журнал модерации
+	result = as(alice, `mutation { createComment(input: {postId: "post_1", content: "http://a.ru http://b.ru"}) { id } }`)
+	spamID := result.Data.(map[string]interface{})["createComment"].(map[string]interface{})["id"].(string)
+	result = as(moder, `mutation { rejectComment(id: "`+spamID+`", reason: "реклама") { status } }`)
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+	entries, err := store.GetAuditLog(ctx, 10)
+	if err != nil || len(entries) != 2 || entries[0].Action != "rejectComment" || entries[0].Details != "реклама" {
+		t.Errorf("Неожиданный журнал: %+v, %v", entries, err)
+	}
+
+	// 6. Правка проходит те же фильтры: отмеченная правка опубликованного комментария отклоняется
+	result = as(alice, `{ post(id: "post_1") { comments { id content version } } }`)
+	var clean map[string]interface{}
+	for _, comment := range result.Data.(map[string]interface{})["post"].(map[string]interface{})["comments"].([]interface{}) {
+		if comment := comment.(map[string]interface{}); comment["content"] == "Хорошая статья" {
+			clean = comment
+		}
+	}
+	if clean == nil {
+		t.Fatalf("Не нашли опубликованный комментарий: %v", result.Data)
+	}
+	result = as(alice, `mutation { updateComment(input: {id: "`+clean["id"].(string)+`", content: "Лучшее казино", version: 1}) { status } }`)
+	if len(result.Errors) == 0 || result.Errors[0].Extensions["code"] != CodeEditFlagged {
+		t.Errorf("Ожидали EDIT_FLAGGED, получили %v", result.Errors)
+	}
+	edited, err := store.GetComment(ctx, clean["id"].(string))
+	if err != nil || edited.Status != models.CommentPublished || edited.Content != "Хорошая статья" {
+		t.Errorf("Ожидали прежний опубликованный комментарий, получили %+v, %v", edited, err)
+	}
+
+	// 7. Ветку комментария на проверке видят автор и модераторы, но не другие пользователи
+	result = as(alice, `mutation { createComment(input: {postId: "post_1", content: "Снова казино"}) { id } }`)
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+	pendingID = result.Data.(map[string]interface{})["createComment"].(map[string]interface{})["id"].(string)
+	thread := `{ commentThread(id: "` + pendingID + `") { comment { id } } }`
+	for _, viewer := range []*auth.Viewer{alice, moder} {
+		if result := as(viewer, thread); len(result.Errors) > 0 {
+			t.Errorf("Ожидали ветку для %s, получили %v", viewer.ID, result.Errors)
+		}
+	}
+	if result := as(bob, thread); len(result.Errors) == 0 || result.Errors[0].Extensions["code"] != CodeNotFound {
+		t.Errorf("Ожидали NOT_FOUND для чужой ветки на проверке, получили %v", result.Errors)
+	}
+}
+
+func TestApproveComment_PublishesOnce(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Контент"})
+
+	hub := pubsub.NewHub(pubsub.DefaultBufferSize)
+	sub := hub.Subscribe("post_1")
+	defer sub.Close()
+
+	schema, err := BuildSchema(Config{
+		Storage:        store,
+		Hub:            hub,
+		ContentFilters: []moderation.ContentFilter{moderation.NewBannedWords([]string{"казино"})},
+	})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+	as := func(viewer *auth.Viewer, request string) *graphql.Result {
+		return graphql.Do(graphql.Params{Schema: *schema, RequestString: request, Context: auth.WithViewer(context.Background(), viewer)})
+	}
+	alice := &auth.Viewer{ID: "alice", Role: auth.RoleUser}
+	moder := &auth.Viewer{ID: "moder", Role: auth.RoleModerator}
+
+	// Комментарий на проверке автор правит, модератор одобряет - подписчики получают его один раз
+	result := as(alice, `mutation { createComment(input: {postId: "post_1", content: "Лучшее казино"}) { id } }`)
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+	id := result.Data.(map[string]interface{})["createComment"].(map[string]interface{})["id"].(string)
+	if result := as(alice, `mutation { updateComment(input: {id: "`+id+`", content: "Хорошая статья", version: 1}) { id } }`); len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+	if result := as(moder, `mutation { approveComment(id: "`+id+`") { id } }`); len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+
+	// Отмеченная правка опубликованного комментария не возвращает его в очередь,
+	// поэтому повторно одобрить и разослать его нельзя
+	as(alice, `mutation { updateComment(input: {id: "`+id+`", content: "Снова казино", version: 2}) { id } }`)
+	result = as(moder, `mutation { approveComment(id: "`+id+`") { id } }`)
+	if len(result.Errors) == 0 || result.Errors[0].Extensions["code"] != CodeNotPending {
+		t.Errorf("Ожидали NOT_PENDING, получили %v", result.Errors)
+	}
+
+	if events := len(sub.C()); events != 1 {
+		t.Errorf("Ожидали одно событие для подписчиков, получили %d", events)
+	}
+}
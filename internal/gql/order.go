@@ -0,0 +1,63 @@
+package gql
+
+import (
+	"graphql-comments/internal/models"
+	"graphql-comments/internal/storage"
+
+	"github.com/graphql-go/graphql"
+)
+
+// CommentOrderFieldEnum - поле сортировки комментариев
+var CommentOrderFieldEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "CommentOrderField",
+	Values: graphql.EnumValueConfigMap{
+		"CREATED_AT":  &graphql.EnumValueConfig{Value: storage.OrderByCreatedAt},
+		"SCORE":       &graphql.EnumValueConfig{Value: storage.OrderByScore},
+		"REPLY_COUNT": &graphql.EnumValueConfig{Value: storage.OrderByReplyCount},
+	},
+})
+
+// OrderDirectionEnum - направление сортировки
+var OrderDirectionEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "OrderDirection",
+	Values: graphql.EnumValueConfigMap{
+		"ASC":  &graphql.EnumValueConfig{Value: "ASC"},
+		"DESC": &graphql.EnumValueConfig{Value: "DESC"},
+	},
+})
+
+// CommentOrderInput - порядок комментариев на каждом уровне дерева
+var CommentOrderInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CommentOrder",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"field":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(CommentOrderFieldEnum)},
+		"direction": &graphql.InputObjectFieldConfig{Type: OrderDirectionEnum, DefaultValue: "ASC"},
+	},
+})
+
+// commentOrderFromArgs читает аргумент orderBy.
+// Второе значение false, если порядок не задан
+func commentOrderFromArgs(args map[string]interface{}) (storage.CommentOrder, bool) {
+	input, ok := args["orderBy"].(map[string]interface{})
+	if !ok {
+		return storage.DefaultCommentOrder, false
+	}
+
+	order := storage.DefaultCommentOrder
+	if field, ok := input["field"].(storage.CommentOrderField); ok {
+		order.Field = field
+	}
+	if direction, ok := input["direction"].(string); ok {
+		order.Desc = direction == "DESC"
+	}
+	return order, true
+}
+
+// sortCommentTree упорядочивает каждый уровень дерева комментариев.
+// Уровни должны быть собраны в порядке создания
+func sortCommentTree(comments []*models.Comment, order storage.CommentOrder) {
+	storage.SortComments(comments, order)
+	for _, comment := range comments {
+		sortCommentTree(comment.Replies, order)
+	}
+}
@@ -0,0 +1,156 @@
+package gql
+
+import (
+	"container/list"
+	"expvar"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"graphql-comments/internal/models"
+)
+
+// OrphanPolicy - что делать с комментарием, родителя которого нет среди комментариев поста
+// (после частичного удаления или импорта)
+type OrphanPolicy string
+
+const (
+	// OrphanDrop - сирота не попадает в дерево
+	OrphanDrop OrphanPolicy = "drop"
+	// OrphanPromote - сирота становится корневым комментарием
+	OrphanPromote OrphanPolicy = "promote"
+	// OrphanPlaceholder - сироты собираются под заглушкой удаленного родителя
+	OrphanPlaceholder OrphanPolicy = "placeholder"
+)
+
+// ParseOrphanPolicy возвращает политику по имени (пустое имя - OrphanDrop)
+func ParseOrphanPolicy(name string) (OrphanPolicy, error) {
+	switch policy := OrphanPolicy(name); policy {
+	case "":
+		return OrphanDrop, nil
+	case OrphanDrop, OrphanPromote, OrphanPlaceholder:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("неизвестная политика для комментариев без родителя: %s", name)
+	}
+}
+
+// orphanComments - счетчик найденных сирот по политикам, доступен на /debug/vars.
+// Каждый сирота учитывается один раз за orphanReportTTL
+var orphanComments = expvar.NewMap("orphan_comments")
+
+const (
+	// orphanReportTTL - через сколько о сироте, который все еще встречается, пишется снова
+	orphanReportTTL = time.Hour
+	// maxReportedOrphans - сколько сирот помнится одновременно, самые старые забываются первыми
+	maxReportedOrphans = 10000
+)
+
+// orphanReports помнит, о каких сиротах уже написано в лог и метрику. Запись живет
+// orphanReportTTL, а число записей ограничено maxReportedOrphans, поэтому удаленные
+// сироты и сироты, чей родитель вернулся, не копятся в памяти все время работы процесса
+type orphanReports struct {
+	mu    sync.Mutex
+	seen  map[string]*list.Element
+	order *list.List // orphanReport в порядке сообщений, самые старые в начале
+}
+
+// orphanReport - когда о сироте сообщили
+type orphanReport struct {
+	id string
+	at time.Time
+}
+
+// first сообщает, что о сироте id нужно написать: его нет среди запомненных
+// или запись о нем устарела. Заодно забывает устаревшие записи
+func (o *orphanReports) first(id string, now time.Time) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.seen == nil {
+		o.seen = make(map[string]*list.Element)
+		o.order = list.New()
+	}
+	for front := o.order.Front(); front != nil; front = o.order.Front() {
+		report := front.Value.(*orphanReport)
+		if now.Sub(report.at) < orphanReportTTL && o.order.Len() < maxReportedOrphans {
+			break
+		}
+		o.order.Remove(front)
+		delete(o.seen, report.id)
+	}
+
+	if _, exists := o.seen[id]; exists {
+		return false
+	}
+	o.seen[id] = o.order.PushBack(&orphanReport{id: id, at: now})
+	return true
+}
+
+// attachOrphans применяет политику к комментариям, чей родитель не найден в commentMap,
+// и возвращает корневой уровень дерева. comments - исходный список в порядке создания
+func (r *ResolverContext) attachOrphans(comments []*models.Comment, commentMap map[string]*models.Comment) []*models.Comment {
+	policy := r.OrphanPolicy
+	if policy == "" {
+		policy = OrphanDrop
+	}
+
+	var roots []*models.Comment
+	placeholders := make(map[string]*models.Comment)
+	for _, comment := range comments {
+		node := commentMap[comment.ID]
+		if comment.ParentID == nil {
+			roots = append(roots, node)
+			continue
+		}
+		parentID := *comment.ParentID
+		if _, exists := commentMap[parentID]; exists {
+			continue
+		}
+
+		// Дерево строится на каждый запрос, а сирота остается сиротой,
+		// поэтому о каждом пишем в лог и метрику один раз за orphanReportTTL
+		if r.reportedOrphans.first(comment.ID, time.Now()) {
+			log.Printf("Комментарий %s поста %s ссылается на отсутствующий комментарий %s, политика %s",
+				comment.ID, comment.PostID, parentID, policy)
+			orphanComments.Add(string(policy), 1)
+		}
+
+		switch policy {
+		case OrphanPromote:
+			roots = append(roots, node)
+		case OrphanPlaceholder:
+			placeholder, exists := placeholders[parentID]
+			if !exists {
+				placeholder = newOrphanPlaceholder(parentID, comment)
+				placeholders[parentID] = placeholder
+				roots = append(roots, placeholder)
+			}
+			placeholder.Replies = append(placeholder.Replies, node)
+			placeholder.ReplyCount++
+		}
+	}
+
+	return roots
+}
+
+// newOrphanPlaceholder создает заглушку отсутствующего родителя.
+// Заглушка выглядит как удаленный комментарий и стоит там, где появился первый из ее ответов
+func newOrphanPlaceholder(parentID string, first *models.Comment) *models.Comment {
+	return &models.Comment{
+		ID:        parentID,
+		PostID:    first.PostID,
+		CreatedAt: first.CreatedAt,
+		UpdatedAt: first.CreatedAt,
+		IsDeleted: true,
+		Status:    models.CommentPublished,
+		Replies:   []*models.Comment{},
+	}
+}
+
+// isOrphanPlaceholder сообщает, что комментарий - заглушка из newOrphanPlaceholder.
+// У настоящих комментариев версия начинается с 1
+func isOrphanPlaceholder(comment *models.Comment) bool {
+	return comment.Version == 0 && comment.IsDeleted
+}
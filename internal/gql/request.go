@@ -0,0 +1,83 @@
+package gql
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+
+	"graphql-comments/internal/persisted"
+)
+
+// maxRequestBodySize - наибольший размер тела HTTP запроса
+const maxRequestBodySize = 1 << 20
+
+// graphQLRequest - запрос GraphQL over HTTP
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+	Extensions    requestExtensions      `json:"extensions"`
+}
+
+// requestExtensions - поддерживаемые расширения запроса
+type requestExtensions struct {
+	PersistedQuery *persisted.Extension `json:"persistedQuery"`
+}
+
+// parseRequest читает запрос из параметров GET (так APQ клиенты отправляют запросы по хэшу,
+// чтобы их кэшировали CDN), из тела POST в JSON, application/graphql или из формы
+func parseRequest(w http.ResponseWriter, r *http.Request) (*graphQLRequest, error) {
+	switch r.Method {
+	case http.MethodGet:
+		return parseValues(r.URL.Query())
+	case http.MethodPost:
+	default:
+		return nil, fmt.Errorf("метод %s не поддерживается", r.Method)
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch contentType {
+	case "application/graphql":
+		query, err := io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения запроса: %w", err)
+		}
+		return &graphQLRequest{Query: string(query)}, nil
+	case "application/x-www-form-urlencoded":
+		r.Body = body
+		if err := r.ParseForm(); err != nil {
+			return nil, fmt.Errorf("ошибка разбора формы: %w", err)
+		}
+		return parseValues(r.Form)
+	default:
+		var req graphQLRequest
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			return nil, fmt.Errorf("ошибка разбора JSON: %w", err)
+		}
+		return &req, nil
+	}
+}
+
+// parseValues читает запрос из параметров адреса или формы:
+// variables и extensions передаются строками JSON
+func parseValues(values url.Values) (*graphQLRequest, error) {
+	req := &graphQLRequest{
+		Query:         values.Get("query"),
+		OperationName: values.Get("operationName"),
+	}
+	if variables := values.Get("variables"); variables != "" {
+		if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+			return nil, fmt.Errorf("ошибка разбора variables: %w", err)
+		}
+	}
+	if extensions := values.Get("extensions"); extensions != "" {
+		if err := json.Unmarshal([]byte(extensions), &req.Extensions); err != nil {
+			return nil, fmt.Errorf("ошибка разбора extensions: %w", err)
+		}
+	}
+	return req, nil
+}
@@ -1,9 +1,15 @@
 package gql
 
 import (
+	"context"
+	"expvar"
 	"graphql-comments/internal/models"
 	"graphql-comments/internal/storage"
+	"strconv"
 	"testing"
+	"time"
+
+	"github.com/graphql-go/graphql"
 )
 
 
@@ -17,7 +23,7 @@ func TestBuildCommentTree_Empty(t *testing.T) {
 	var comments []*models.Comment
 
 	// Вызываем тестируемую функцию
-	result := resolver.buildCommentTree(comments)
+	result := resolver.buildCommentTree(comments, storage.DefaultCommentOrder)
 
 	// Проверяем: результат должен быть пустым массивом
 	if len(result) != 0 {
@@ -39,7 +45,7 @@ func TestBuildCommentTree_RootComments(t *testing.T) {
 	}
 
 	// buildCommentTree должна вернуть оба комментария на верхнем уровне
-	result := resolver.buildCommentTree(comments)
+	result := resolver.buildCommentTree(comments, storage.DefaultCommentOrder)
 
 	// Проверяем что оба комментария стали корневыми
 	if len(result) != 2 {
@@ -63,7 +69,7 @@ func TestBuildCommentTree_WithReplies(t *testing.T) {
 		{ID: "comment_3", PostID: "post_1", ParentID: &parentID2, Content: "Ответ на ответ", Replies: []*models.Comment{}},
 	}
 
-	result := resolver.buildCommentTree(comments)
+	result := resolver.buildCommentTree(comments, storage.DefaultCommentOrder)
 
 	// Должен быть только 1 корневой комментарий
 	if len(result) != 1 {
@@ -108,9 +114,9 @@ func TestBuildCommentTree_BrokenParent(t *testing.T) {
 		},
 	}
 
-	result := resolver.buildCommentTree(comments)
+	result := resolver.buildCommentTree(comments, storage.DefaultCommentOrder)
 
-	// В текущей реализации: comment_1 теряется, comment_2 становится корневым
+	// По умолчанию (OrphanDrop): comment_1 отбрасывается, comment_2 остается корневым
 	// Ожидаем только 1 корневой комментарий (comment_2)
 	if len(result) != 1 {
 		t.Errorf("Ожидали 1 корневой комментарий, получили %d", len(result))
@@ -122,3 +128,500 @@ func TestBuildCommentTree_BrokenParent(t *testing.T) {
 	}
 
 }
+
+func TestBuildCommentTree_OrphanPolicies(t *testing.T) {
+	missingID := "comment_404"
+	newComments := func() []*models.Comment {
+		return []*models.Comment{
+			{ID: "comment_1", PostID: "post_1", Content: "Корневой", Version: 1},
+			{ID: "comment_2", PostID: "post_1", ParentID: &missingID, Content: "Сирота", Version: 1},
+			{ID: "comment_3", PostID: "post_1", ParentID: &missingID, Content: "Сирота", Version: 1},
+		}
+	}
+
+	tests := []struct {
+		policy OrphanPolicy
+		roots  []string
+	}{
+		{OrphanDrop, []string{"comment_1"}},
+		{OrphanPromote, []string{"comment_1", "comment_2", "comment_3"}},
+		{OrphanPlaceholder, []string{"comment_1", "comment_404"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			resolver := &ResolverContext{OrphanPolicy: tt.policy}
+			before := orphanCount(tt.policy)
+
+			result := resolver.buildCommentTree(newComments(), storage.DefaultCommentOrder)
+			if len(result) != len(tt.roots) {
+				t.Fatalf("Ожидали %d корневых комментариев, получили %d", len(tt.roots), len(result))
+			}
+			for i, id := range tt.roots {
+				if result[i].ID != id {
+					t.Errorf("Ожидали %s на позиции %d, получили %s", id, i, result[i].ID)
+				}
+			}
+
+			// Каждый сирота учитывается в метрике один раз, сколько бы раз ни строилось дерево
+			resolver.buildCommentTree(newComments(), storage.DefaultCommentOrder)
+			if got := orphanCount(tt.policy) - before; got != 2 {
+				t.Errorf("Ожидали прирост счетчика на 2, получили %d", got)
+			}
+		})
+	}
+
+	// Заглушка выглядит как удаленный комментарий с двумя ответами
+	resolver := &ResolverContext{OrphanPolicy: OrphanPlaceholder}
+	placeholder := resolver.buildCommentTree(newComments(), storage.DefaultCommentOrder)[1]
+	if !placeholder.IsDeleted || len(placeholder.Replies) != 2 || placeholder.ReplyCount != 2 {
+		t.Errorf("Неожиданная заглушка: %+v", placeholder)
+	}
+}
+
+func TestOrphanReports_TTLAndLimit(t *testing.T) {
+	var reports orphanReports
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Повторно о сироте сообщаем только после orphanReportTTL
+	if !reports.first("comment_1", now) || reports.first("comment_1", now.Add(time.Minute)) {
+		t.Error("Ожидали одно сообщение о сироте в пределах TTL")
+	}
+	if !reports.first("comment_1", now.Add(orphanReportTTL)) {
+		t.Error("Ожидали повторное сообщение после TTL")
+	}
+
+	// Записей не больше maxReportedOrphans, самые старые забываются первыми
+	for i := 0; i < maxReportedOrphans+10; i++ {
+		reports.first("orphan_"+strconv.Itoa(i), now.Add(orphanReportTTL))
+	}
+	if len(reports.seen) != maxReportedOrphans || reports.order.Len() != maxReportedOrphans {
+		t.Errorf("Ожидали %d записей, получили %d", maxReportedOrphans, len(reports.seen))
+	}
+	if !reports.first("orphan_0", now.Add(orphanReportTTL)) {
+		t.Error("Ожидали, что самая старая запись забыта")
+	}
+}
+
+// orphanCount возвращает значение счетчика сирот для политики
+func orphanCount(policy OrphanPolicy) int64 {
+	if value, ok := orphanComments.Get(string(policy)).(*expvar.Int); ok {
+		return value.Value()
+	}
+	return 0
+}
+
+func TestPostsConnection_Pagination(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	for _, id := range []string{"post_1", "post_2", "post_3"} {
+		store.CreatePost(ctx, &models.Post{ID: id, Title: "Пост", Content: "Контент"})
+	}
+
+	schema, err := BuildSchema(Config{Storage: store})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+
+	// Первая страница из двух постов
+	result := graphql.Do(graphql.Params{
+		Schema:        *schema,
+		RequestString: `{ postsConnection(first: 2) { totalCount edges { node { id } } pageInfo { hasNextPage endCursor } } }`,
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+
+	connection := result.Data.(map[string]interface{})["postsConnection"].(map[string]interface{})
+	edges := connection["edges"].([]interface{})
+	if len(edges) != 2 {
+		t.Fatalf("Ожидали 2 поста, получили %d", len(edges))
+	}
+	// Новые посты идут первыми
+	if id := edges[0].(map[string]interface{})["node"].(map[string]interface{})["id"]; id != "post_3" {
+		t.Errorf("Ожидали post_3 первым, получили %v", id)
+	}
+	pageInfo := connection["pageInfo"].(map[string]interface{})
+	if pageInfo["hasNextPage"] != true {
+		t.Errorf("Ожидали hasNextPage = true")
+	}
+
+	// Вторая страница по курсору
+	result = graphql.Do(graphql.Params{
+		Schema:         *schema,
+		RequestString:  `query($after: String) { postsConnection(first: 2, after: $after) { edges { node { id } } } }`,
+		VariableValues: map[string]interface{}{"after": pageInfo["endCursor"]},
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+	edges = result.Data.(map[string]interface{})["postsConnection"].(map[string]interface{})["edges"].([]interface{})
+	if len(edges) != 1 {
+		t.Errorf("Ожидали 1 пост на второй странице, получили %d", len(edges))
+	}
+}
+
+func TestComments_MaxDepthWithLazyReplies(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Контент"})
+
+	c1, c2 := "c1", "c2"
+	store.CreateComment(ctx, &models.Comment{ID: "c1", PostID: "post_1", Content: "Корневой"})
+	store.CreateComment(ctx, &models.Comment{ID: "c2", PostID: "post_1", ParentID: &c1, Content: "Ответ"})
+	store.CreateComment(ctx, &models.Comment{ID: "c3", PostID: "post_1", ParentID: &c2, Content: "Ответ на ответ"})
+
+	schema, err := BuildSchema(Config{Storage: store})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+
+	// Загружаем только корневой уровень, ответы подгружаются лениво
+	result := graphql.Do(graphql.Params{
+		Schema:        *schema,
+		RequestString: `{ posts { comments(maxDepth: 1) { id replies(first: 10) { id replies { id } } } } }`,
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+
+	post := result.Data.(map[string]interface{})["posts"].([]interface{})[0].(map[string]interface{})
+	root := post["comments"].([]interface{})[0].(map[string]interface{})
+	replies := root["replies"].([]interface{})
+	if len(replies) != 1 {
+		t.Fatalf("Ожидали 1 ответ, получили %d", len(replies))
+	}
+	nested := replies[0].(map[string]interface{})["replies"].([]interface{})
+	if len(nested) != 1 || nested[0].(map[string]interface{})["id"] != "c3" {
+		t.Errorf("Ожидали ответ c3 на втором уровне, получили %v", nested)
+	}
+}
+
+func TestCreateComment_ValidationError(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Контент"})
+
+	schema, err := BuildSchema(Config{Storage: store})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+
+	// Пустой комментарий должен быть отклонен с кодом VALIDATION_FAILED
+	result := graphql.Do(graphql.Params{
+		Schema:        *schema,
+		RequestString: `mutation { createComment(input: {postId: "post_1", content: "   "}) { id } }`,
+	})
+	if len(result.Errors) != 1 {
+		t.Fatalf("Ожидали 1 ошибку, получили %v", result.Errors)
+	}
+	if code := result.Errors[0].Extensions["code"]; code != "VALIDATION_FAILED" {
+		t.Errorf("Ожидали код VALIDATION_FAILED, получили %v", code)
+	}
+
+	// Комментарий не сохранился
+	comments, _ := store.GetCommentsByPostID(ctx, "post_1")
+	if len(comments) != 0 {
+		t.Errorf("Ожидали 0 комментариев, получили %d", len(comments))
+	}
+}
+
+func TestCreatePost_TimestampsAndAuthor(t *testing.T) {
+	schema, err := BuildSchema(Config{Storage: storage.NewMemoryStorage()})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:        *schema,
+		RequestString: `mutation { createPost(title: "Пост", content: "Текст", author: "alice") { author createdAt updatedAt } }`,
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+
+	post := result.Data.(map[string]interface{})["createPost"].(map[string]interface{})
+	if post["author"] != "alice" {
+		t.Errorf("Ожидали автора alice, получили %v", post["author"])
+	}
+
+	// createdAt приходит строкой RFC 3339
+	createdAt, _ := post["createdAt"].(string)
+	if _, err := time.Parse(time.RFC3339, createdAt); err != nil {
+		t.Errorf("createdAt не в формате RFC 3339: %q", createdAt)
+	}
+	if post["updatedAt"] != post["createdAt"] {
+		t.Errorf("У нового поста updatedAt должен совпадать с createdAt")
+	}
+}
+
+func TestUpdateComment_VersionAndRevisions(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Текст"})
+	store.CreateComment(ctx, &models.Comment{ID: "comment_1", PostID: "post_1", Content: "Первая версия"})
+
+	schema, err := BuildSchema(Config{Storage: store})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+
+	update := `mutation { updateComment(input: {id: "comment_1", content: "Вторая версия", version: 1}) {
+		content version revisions { version content }
+	} }`
+	result := graphql.Do(graphql.Params{Schema: *schema, RequestString: update})
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+
+	comment := result.Data.(map[string]interface{})["updateComment"].(map[string]interface{})
+	if comment["content"] != "Вторая версия" || comment["version"] != 2 {
+		t.Errorf("Ожидали новую версию 2, получили %v", comment)
+	}
+	revisions := comment["revisions"].([]interface{})
+	if len(revisions) != 1 || revisions[0].(map[string]interface{})["content"] != "Первая версия" {
+		t.Errorf("Ожидали одну правку с первой версией текста, получили %v", revisions)
+	}
+
+	// Повторная правка с той же (устаревшей) версией должна вернуть конфликт
+	result = graphql.Do(graphql.Params{Schema: *schema, RequestString: update})
+	if len(result.Errors) == 0 || result.Errors[0].Message != storage.ErrConflict.Error() {
+		t.Errorf("Ожидали ошибку конфликта версий, получили %v", result.Errors)
+	}
+}
+
+func TestCommentThread_AncestorsAndSubtree(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Текст"})
+
+	// Цепочка comment_1 -> comment_2 -> comment_3 -> comment_4
+	var parentID *string
+	for _, id := range []string{"comment_1", "comment_2", "comment_3", "comment_4"} {
+		store.CreateComment(ctx, &models.Comment{ID: id, PostID: "post_1", ParentID: parentID, Content: id})
+		parent := id
+		parentID = &parent
+	}
+
+	schema, err := BuildSchema(Config{Storage: store})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema: *schema,
+		RequestString: `{ commentThread(id: "comment_3", ancestors: 1) {
+			ancestors { id }
+			comment { id replies { id } }
+		} }`,
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+
+	thread := result.Data.(map[string]interface{})["commentThread"].(map[string]interface{})
+	ancestors := thread["ancestors"].([]interface{})
+	if len(ancestors) != 1 || ancestors[0].(map[string]interface{})["id"] != "comment_2" {
+		t.Errorf("Ожидали одного ближайшего предка comment_2, получили %v", ancestors)
+	}
+
+	replies := thread["comment"].(map[string]interface{})["replies"].([]interface{})
+	if len(replies) != 1 || replies[0].(map[string]interface{})["id"] != "comment_4" {
+		t.Errorf("Ожидали ответ comment_4 в поддереве, получили %v", replies)
+	}
+}
+
+func TestDeleteComment_SoftDeleteKeepsReplies(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Текст"})
+	parentID := "comment_1"
+	store.CreateComment(ctx, &models.Comment{ID: "comment_1", PostID: "post_1", Content: "Родитель"})
+	store.CreateComment(ctx, &models.Comment{ID: "comment_2", PostID: "post_1", ParentID: &parentID, Content: "Ответ"})
+
+	schema, err := BuildSchema(Config{Storage: store, SoftDelete: true})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+
+	result := graphql.Do(graphql.Params{Schema: *schema, RequestString: `mutation { deleteComment(id: "comment_1") }`})
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+
+	result = graphql.Do(graphql.Params{
+		Schema:        *schema,
+		RequestString: `{ comment(id: "comment_1") { content isDeleted replies { content } } }`,
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+
+	comment := result.Data.(map[string]interface{})["comment"].(map[string]interface{})
+	if comment["isDeleted"] != true || comment["content"] != deletedCommentContent {
+		t.Errorf("Ожидали заглушку со скрытым текстом, получили %v", comment)
+	}
+	replies := comment["replies"].([]interface{})
+	if len(replies) != 1 || replies[0].(map[string]interface{})["content"] != "Ответ" {
+		t.Errorf("Ответ на удаленный комментарий должен остаться, получили %v", replies)
+	}
+}
+
+func TestComments_OrderBy(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Контент"})
+
+	c1, c2 := "c1", "c2"
+	store.CreateComment(ctx, &models.Comment{ID: "c1", PostID: "post_1", Content: "Первый"})
+	store.CreateComment(ctx, &models.Comment{ID: "c2", PostID: "post_1", Content: "Второй"})
+	store.CreateComment(ctx, &models.Comment{ID: "c3", PostID: "post_1", ParentID: &c1, Content: "Ответ"})
+	store.CreateComment(ctx, &models.Comment{ID: "c4", PostID: "post_1", ParentID: &c1, Content: "Ответ"})
+	store.CreateComment(ctx, &models.Comment{ID: "c5", PostID: "post_1", ParentID: &c2, Content: "Ответ"})
+
+	schema, err := BuildSchema(Config{Storage: store})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+
+	ids := func(list interface{}) []string {
+		var result []string
+		for _, item := range list.([]interface{}) {
+			result = append(result, item.(map[string]interface{})["id"].(string))
+		}
+		return result
+	}
+	query := func(request string) []interface{} {
+		result := graphql.Do(graphql.Params{Schema: *schema, RequestString: request})
+		if len(result.Errors) > 0 {
+			t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+		}
+		post := result.Data.(map[string]interface{})["post"].(map[string]interface{})
+		return post["comments"].([]interface{})
+	}
+
+	// 1. По умолчанию - хронологический порядок, одинаковый на каждом запросе
+	for i := 0; i < 5; i++ {
+		roots := query(`{ post(id: "post_1") { comments { id replies { id } } } }`)
+		if got := ids(roots); len(got) != 2 || got[0] != "c1" || got[1] != "c2" {
+			t.Fatalf("Ожидали c1, c2, получили %v", got)
+		}
+		if got := ids(roots[0].(map[string]interface{})["replies"]); got[0] != "c3" || got[1] != "c4" {
+			t.Fatalf("Ожидали ответы c3, c4, получили %v", got)
+		}
+	}
+
+	// 2. Сортировка применяется на каждом уровне дерева
+	roots := query(`{ post(id: "post_1") { comments(orderBy: {field: CREATED_AT, direction: DESC}) { id replies { id } } } }`)
+	if got := ids(roots); got[0] != "c2" || got[1] != "c1" {
+		t.Errorf("Ожидали c2, c1, получили %v", got)
+	}
+	if got := ids(roots[1].(map[string]interface{})["replies"]); got[0] != "c4" || got[1] != "c3" {
+		t.Errorf("Ожидали ответы c4, c3, получили %v", got)
+	}
+
+	// 3. По числу ответов: у c1 их два, у c2 один
+	roots = query(`{ post(id: "post_1") { comments(orderBy: {field: REPLY_COUNT, direction: ASC}) { id replyCount } } }`)
+	if got := ids(roots); got[0] != "c2" || got[1] != "c1" {
+		t.Errorf("Ожидали c2, c1, получили %v", got)
+	}
+	if count := roots[1].(map[string]interface{})["replyCount"]; count != 2 {
+		t.Errorf("Ожидали replyCount 2, получили %v", count)
+	}
+}
+
+func TestVoteAndReact(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Контент"})
+	store.CreateComment(ctx, &models.Comment{ID: "c1", PostID: "post_1", Content: "Первый"})
+	store.CreateComment(ctx, &models.Comment{ID: "c2", PostID: "post_1", Content: "Второй"})
+
+	schema, err := BuildSchema(Config{Storage: store})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+	do := func(request string) *graphql.Result {
+		return graphql.Do(graphql.Params{Schema: *schema, RequestString: request})
+	}
+
+	// 1. Голоса и реакция на c2
+	for _, request := range []string{
+		`mutation { voteComment(id: "c2", value: 1, user: "alice") { id } }`,
+		`mutation { voteComment(id: "c2", value: 1, user: "bob") { id } }`,
+		`mutation { react(id: "c2", emoji: "🔥", user: "alice") { id } }`,
+	} {
+		if result := do(request); len(result.Errors) > 0 {
+			t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+		}
+	}
+
+	// 2. Рейтинг, реакции и реакция текущего пользователя; сортировка по рейтингу
+	result := do(`{ post(id: "post_1") { comments(orderBy: {field: SCORE, direction: DESC}) {
+		id score reactionCounts { emoji count } viewerReaction(user: "alice") { value emoji }
+	} } }`)
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+	comments := result.Data.(map[string]interface{})["post"].(map[string]interface{})["comments"].([]interface{})
+	top := comments[0].(map[string]interface{})
+	if top["id"] != "c2" || top["score"] != 2 {
+		t.Fatalf("Ожидали c2 с рейтингом 2 первым, получили %v", top)
+	}
+	counts := top["reactionCounts"].([]interface{})
+	if len(counts) != 1 || counts[0].(map[string]interface{})["count"] != 1 {
+		t.Errorf("Ожидали одну реакцию 🔥, получили %v", counts)
+	}
+	viewer := top["viewerReaction"].(map[string]interface{})
+	if viewer["value"] != 1 || viewer["emoji"] != "🔥" {
+		t.Errorf("Неожиданная реакция alice: %v", viewer)
+	}
+
+	// 3. Недопустимый голос отклоняется
+	result = do(`mutation { voteComment(id: "c1", value: 5, user: "alice") { id } }`)
+	if len(result.Errors) == 0 || result.Errors[0].Extensions["code"] != CodeBadUserInput {
+		t.Errorf("Ожидали ошибку BAD_USER_INPUT, получили %v", result.Errors)
+	}
+}
+
+func TestAllowComments_CreateAndToggle(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	schema, err := BuildSchema(Config{Storage: store})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+	do := func(request string) *graphql.Result {
+		return graphql.Do(graphql.Params{Schema: *schema, RequestString: request})
+	}
+
+	// 1. Пост без аргумента принимает комментарии, с allowComments: false - нет
+	result := do(`mutation { open: createPost(title: "Открытый", content: "Текст") { id allowComments }
+		closed: createPost(title: "Закрытый", content: "Текст", allowComments: false) { id allowComments } }`)
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+	data := result.Data.(map[string]interface{})
+	if data["open"].(map[string]interface{})["allowComments"] != true {
+		t.Errorf("Ожидали разрешенные комментарии по умолчанию, получили %v", data["open"])
+	}
+	closed := data["closed"].(map[string]interface{})
+	if closed["allowComments"] != false {
+		t.Errorf("Ожидали отключенные комментарии, получили %v", closed)
+	}
+	closedID := closed["id"].(string)
+
+	result = do(`mutation { createComment(input: {postId: "` + closedID + `", content: "Текст"}) { id } }`)
+	if len(result.Errors) == 0 || result.Errors[0].Extensions["code"] != CodeCommentsDisabled {
+		t.Errorf("Ожидали COMMENTS_DISABLED, получили %v", result.Errors)
+	}
+
+	// 2. После включения комментарии принимаются
+	result = do(`mutation { setAllowComments(postId: "` + closedID + `", allowComments: true) { allowComments } }`)
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+	result = do(`mutation { createComment(input: {postId: "` + closedID + `", content: "Текст"}) { id } }`)
+	if len(result.Errors) > 0 {
+		t.Errorf("Ожидали комментарий после включения, получили %v", result.Errors)
+	}
+}
@@ -1,45 +1,236 @@
 package gql
 
 import (
-	"strconv"
-	"sync"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
 
+	"graphql-comments/internal/auth"
+	"graphql-comments/internal/idgen"
 	"graphql-comments/internal/models"
+	"graphql-comments/internal/moderation"
+	"graphql-comments/internal/policy"
+	"graphql-comments/internal/pubsub"
+	"graphql-comments/internal/ratelimit"
 	"graphql-comments/internal/storage"
+	"graphql-comments/internal/validation"
 
 	"github.com/graphql-go/graphql"
 )
 
+// deletedCommentContent - текст, который показывается вместо удаленного комментария
+const deletedCommentContent = "[комментарий удален]"
+
+// hiddenCommentContent - текст, который видят все, кроме модераторов, вместо скрытого комментария
+const hiddenCommentContent = "[комментарий скрыт модератором]"
+
+// anonymousAuthor - автор по умолчанию, если он не передан в мутации
+const anonymousAuthor = "anonymous"
+
 // ResolverContext хранит зависимости для резолверов
 type ResolverContext struct {
-	Storage        storage.Storage
-	mu             sync.Mutex
-	postCounter    int
-	commentCounter int
+	Storage storage.Storage
+	Hub     *pubsub.Hub
+	IDs     idgen.Generator
+	Limits  validation.Limits
+	// SoftDelete - удаленные комментарии остаются в дереве заглушками
+	SoftDelete bool
+	// OrphanPolicy - что делать с комментариями без родителя при построении дерева
+	OrphanPolicy OrphanPolicy
+	// Policy - проверка прав, к которой обращается каждая мутация до хранилища
+	Policy policy.Policy
+	// Moderation - фильтры новых комментариев и правок (nil - комментарии публикуются сразу)
+	Moderation *moderation.Pipeline
+	// Limiter - лимиты на создание постов и комментариев (nil - без ограничений)
+	Limiter *ratelimit.Limiter
+
+	// reportedOrphans - сироты, о которых уже написано в лог и метрику
+	reportedOrphans orphanReports
 }
 
 // PostsResolver возвращает все посты
 func (r *ResolverContext) PostsResolver(p graphql.ResolveParams) (interface{}, error) {
-	posts, err := r.Storage.GetAllPosts()
+	posts, err := r.Storage.GetAllPosts(p.Context)
 	if err != nil {
 		return nil, err
 	}
 	return posts, nil
 }
 
+// PostResolver возвращает пост по ID
+func (r *ResolverContext) PostResolver(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+
+	post, err := r.Storage.GetPost(p.Context, id)
+	if err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// CommentResolver возвращает комментарий по ID (ответы подгружаются лениво)
+func (r *ResolverContext) CommentResolver(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+
+	comment, err := r.Storage.GetComment(p.Context, id)
+	if err != nil {
+		return nil, err
+	}
+	if !canSeeComment(p.Context, comment) {
+		return nil, storage.ErrCommentNotFound
+	}
+	comment.Replies = nil
+	return comment, nil
+}
+
+// CommentThreadResolver возвращает комментарий с поддеревом ответов и цепочкой его предков.
+// Нужен для ссылки на отдельный ответ без загрузки всего поста
+func (r *ResolverContext) CommentThreadResolver(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+
+	// Без ancestors возвращаем всю цепочку до корневого комментария
+	limit, limited := p.Args["ancestors"].(int)
+	if limited && limit < 0 {
+		return nil, badUserInput("ancestors не может быть отрицательным")
+	}
+
+	target, err := r.Storage.GetComment(p.Context, id)
+	if err != nil {
+		return nil, err
+	}
+	if !canSeeComment(p.Context, target) {
+		return nil, storage.ErrCommentNotFound
+	}
+
+	// Читаем только ветку комментария, а не весь пост
+	subtree, err := r.Storage.GetCommentSubtree(p.Context, target.ID)
+	if err != nil {
+		return nil, err
+	}
+	comment := linkCommentTree(append([]*models.Comment{target}, subtree...))[target.ID]
+
+	if !limited {
+		limit = -1
+	}
+	ancestors, err := r.Storage.GetCommentAncestors(p.Context, target.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	// Ответы предков не отдаем целиком - RepliesResolver подгрузит их по запросу
+	for _, ancestor := range ancestors {
+		ancestor.Replies = nil
+	}
+
+	return &models.CommentThread{Comment: comment, Ancestors: ancestors}, nil
+}
+
+// PostsConnectionResolver возвращает страницу постов в виде Relay-соединения
+func (r *ResolverContext) PostsConnectionResolver(p graphql.ResolveParams) (interface{}, error) {
+	page, err := pageParamsFromArgs(p.Args, postCursorPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	postPage, err := r.Storage.GetPostsPage(p.Context, page)
+	if err != nil {
+		return nil, err
+	}
+
+	connection := &models.PostConnection{
+		Edges: make([]*models.PostEdge, 0, len(postPage.Posts)),
+		PageInfo: &models.PageInfo{
+			HasNextPage:     postPage.HasNextPage,
+			HasPreviousPage: postPage.HasPreviousPage,
+		},
+		TotalCount: postPage.TotalCount,
+	}
+	for _, post := range postPage.Posts {
+		connection.Edges = append(connection.Edges, &models.PostEdge{
+			Cursor: encodeCursor(postCursorPrefix, post.ID),
+			Node:   post,
+		})
+	}
+	if len(connection.Edges) > 0 {
+		connection.PageInfo.StartCursor = &connection.Edges[0].Cursor
+		connection.PageInfo.EndCursor = &connection.Edges[len(connection.Edges)-1].Cursor
+	}
+
+	return connection, nil
+}
+
 // CreatePostResolver создает новый пост
 func (r *ResolverContext) CreatePostResolver(p graphql.ResolveParams) (interface{}, error) {
 	title, _ := p.Args["title"].(string)
 	content, _ := p.Args["content"].(string)
 
+	if err := r.Limits.ValidatePost(title, content); err != nil {
+		return nil, err
+	}
+
+	author, err := r.authorFor(p.Context, policy.CreatePost, p.Args["author"], "author")
+	if err != nil {
+		return nil, err
+	}
+	if r.Limiter != nil {
+		if err := r.Limiter.AllowPost(p.Context); err != nil {
+			return nil, err
+		}
+	}
+
+	// По умолчанию комментарии разрешены
+	allowComments := true
+	if allow, ok := p.Args["allowComments"].(bool); ok {
+		allowComments = allow
+	}
+
+	id, err := r.generatePostID(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
 	post := &models.Post{
-		ID:       r.generatePostID(),
-		Title:    title,
-		Content:  content,
-		Comments: []*models.Comment{},
+		ID:               id,
+		Title:            title,
+		Content:          content,
+		Comments:         []*models.Comment{},
+		CommentsDisabled: !allowComments,
+		Author:           author,
 	}
 
-	err := r.Storage.CreatePost(post)
+	err = r.Storage.CreatePost(p.Context, post)
+	if err != nil {
+		return nil, err
+	}
+
+	return post, nil
+}
+
+// AllowCommentsResolver возвращает, разрешены ли комментарии к посту
+func (r *ResolverContext) AllowCommentsResolver(p graphql.ResolveParams) (interface{}, error) {
+	post, ok := p.Source.(*models.Post)
+	return ok && !post.CommentsDisabled, nil
+}
+
+// SetAllowCommentsResolver включает или отключает комментарии к посту
+func (r *ResolverContext) SetAllowCommentsResolver(p graphql.ResolveParams) (interface{}, error) {
+	postID, _ := p.Args["postId"].(string)
+	allow, _ := p.Args["allowComments"].(bool)
+
+	viewer, moderated, err := r.authorizePost(p.Context, policy.SetAllowComments, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	// В журнал попадают только действия с чужими постами
+	if !moderated {
+		viewer = nil
+	}
+	var post *models.Post
+	err = r.audited(p.Context, viewer, string(policy.SetAllowComments), postID, fmt.Sprintf("allowComments=%t", allow), func(ctx context.Context) (err error) {
+		post, err = r.Storage.SetAllowComments(ctx, postID, allow)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -51,7 +242,17 @@ func (r *ResolverContext) CreatePostResolver(p graphql.ResolveParams) (interface
 func (r *ResolverContext) DeletePostResolver(p graphql.ResolveParams) (interface{}, error) {
 	id, _ := p.Args["id"].(string)
 
-	err := r.Storage.DeletePost(id)
+	viewer, moderated, err := r.authorizePost(p.Context, policy.DeletePost, id)
+	if err != nil {
+		return false, err
+	}
+
+	if !moderated {
+		viewer = nil
+	}
+	err = r.audited(p.Context, viewer, string(policy.DeletePost), id, "", func(ctx context.Context) error {
+		return r.Storage.DeletePost(ctx, id)
+	})
 	if err != nil {
 		return false, err
 	}
@@ -59,6 +260,35 @@ func (r *ResolverContext) DeletePostResolver(p graphql.ResolveParams) (interface
 	return true, nil
 }
 
+// UpdatePostResolver обновляет заголовок и/или текст поста
+func (r *ResolverContext) UpdatePostResolver(p graphql.ResolveParams) (interface{}, error) {
+	input, _ := p.Args["input"].(map[string]interface{})
+
+	update := &models.UpdatePostInput{}
+	update.ID, _ = input["id"].(string)
+	update.Version, _ = input["version"].(int)
+	if title, ok := input["title"].(string); ok {
+		update.Title = &title
+	}
+	if content, ok := input["content"].(string); ok {
+		update.Content = &content
+	}
+
+	if err := r.Limits.ValidatePostUpdate(update.Title, update.Content, "input"); err != nil {
+		return nil, err
+	}
+	if _, _, err := r.authorizePost(p.Context, policy.UpdatePost, update.ID); err != nil {
+		return nil, err
+	}
+
+	post, err := r.Storage.UpdatePost(p.Context, update)
+	if err != nil {
+		return nil, err
+	}
+
+	return post, nil
+}
+
 // CreateCommentResolver создает новый комментарий
 func (r *ResolverContext) CreateCommentResolver(p graphql.ResolveParams) (interface{}, error) {
 	// Получаем input объект
@@ -67,33 +297,142 @@ func (r *ResolverContext) CreateCommentResolver(p graphql.ResolveParams) (interf
 	postID, _ := input["postId"].(string)
 	content, _ := input["content"].(string)
 
+	if err := r.Limits.ValidateComment(content, "input", "content"); err != nil {
+		return nil, err
+	}
+
+	author, err := r.authorFor(p.Context, policy.CreateComment, input["author"], "input", "author")
+	if err != nil {
+		return nil, err
+	}
+	if r.Limiter != nil {
+		if err := r.Limiter.AllowComment(p.Context, postID); err != nil {
+			return nil, err
+		}
+	}
+
 	// ParentID может быть nil или строкой
 	var parentID *string
 	if parentArg, ok := input["parentId"].(string); ok {
 		parentID = &parentArg
 	}
 
+	id, err := r.generateCommentID(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
 	comment := &models.Comment{
-		ID:       r.generateCommentID(),
+		ID:       id,
 		PostID:   postID,
 		ParentID: parentID,
 		Content:  content,
 		Replies:  []*models.Comment{},
+		Author:   author,
+	}
+
+	// Отмеченный фильтрами комментарий сохраняется, но ждет проверки модератором
+	if r.Moderation != nil {
+		if verdict := r.Moderation.Check(p.Context, comment); verdict.Flagged {
+			comment.Status = models.CommentPending
+			comment.ModerationReason = verdict.Reason
+		}
 	}
 
-	err := r.Storage.CreateComment(comment)
+	err = r.Storage.CreateComment(p.Context, comment)
 	if err != nil {
 		return nil, err
 	}
 
+	// Оповещаем подписчиков только после успешного сохранения и публикации.
+	// Подписчики читают комментарий в своих горутинах, поэтому получают копию
+	if r.Hub != nil && comment.Status == models.CommentPublished {
+		published := *comment
+		r.Hub.Publish(&published)
+	}
+
 	return comment, nil
 }
 
+// UpdateCommentResolver обновляет текст комментария
+func (r *ResolverContext) UpdateCommentResolver(p graphql.ResolveParams) (interface{}, error) {
+	input, _ := p.Args["input"].(map[string]interface{})
+
+	update := &models.UpdateCommentInput{}
+	update.ID, _ = input["id"].(string)
+	update.Content, _ = input["content"].(string)
+	update.Version, _ = input["version"].(int)
+
+	if err := r.Limits.ValidateComment(update.Content, "input", "content"); err != nil {
+		return nil, err
+	}
+	if _, _, err := r.authorizeComment(p.Context, policy.UpdateComment, update.ID); err != nil {
+		return nil, err
+	}
+
+	// Правка проходит те же фильтры, что и новый комментарий. Отмеченную правку
+	// опубликованного комментария хранилище отклонит с ErrEditFlagged
+	if r.Moderation != nil {
+		current, err := r.Storage.GetComment(p.Context, update.ID)
+		if err != nil {
+			return nil, err
+		}
+		edited := *current
+		edited.Content = update.Content
+		if verdict := r.Moderation.Check(p.Context, &edited); verdict.Flagged {
+			update.ModerationReason = verdict.Reason
+		}
+	}
+
+	comment, err := r.Storage.UpdateComment(p.Context, update)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ответы у отредактированного комментария подгружаются лениво
+	comment.Replies = nil
+	return comment, nil
+}
+
+// RevisionsResolver возвращает историю правок комментария
+func (r *ResolverContext) RevisionsResolver(p graphql.ResolveParams) (interface{}, error) {
+	comment, ok := p.Source.(*models.Comment)
+	if !ok {
+		return nil, nil
+	}
+
+	// История удаленного, скрытого или недоступного комментария раскрыла бы его текст:
+	// правила те же, что в CommentContentResolver
+	if comment.IsDeleted || !canSeeComment(p.Context, comment) {
+		return []*models.CommentRevision{}, nil
+	}
+	if comment.IsHidden && !auth.ViewerFromContext(p.Context).HasRole(auth.RoleModerator) {
+		return []*models.CommentRevision{}, nil
+	}
+
+	return r.Storage.GetCommentRevisions(p.Context, comment.ID)
+}
+
 // DeleteCommentResolver удаляет комментарий по ID
 func (r *ResolverContext) DeleteCommentResolver(p graphql.ResolveParams) (interface{}, error) {
 	id, _ := p.Args["id"].(string)
 
-	err := r.Storage.DeleteComment(id)
+	viewer, moderated, err := r.authorizeComment(p.Context, policy.DeleteComment, id)
+	if err != nil {
+		return false, err
+	}
+
+	if !moderated {
+		viewer = nil
+	}
+	err = r.audited(p.Context, viewer, string(policy.DeleteComment), id, "", func(ctx context.Context) error {
+		// В режиме мягкого удаления ответы остаются видимыми под заглушкой
+		if r.SoftDelete {
+			_, err := r.Storage.SoftDeleteComment(ctx, id)
+			return err
+		}
+		return r.Storage.DeleteComment(ctx, id)
+	})
 	if err != nil {
 		return false, err
 	}
@@ -101,6 +440,158 @@ func (r *ResolverContext) DeleteCommentResolver(p graphql.ResolveParams) (interf
 	return true, nil
 }
 
+// RestoreCommentResolver восстанавливает мягко удаленный комментарий
+func (r *ResolverContext) RestoreCommentResolver(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+
+	viewer, moderated, err := r.authorizeComment(p.Context, policy.RestoreComment, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !moderated {
+		viewer = nil
+	}
+	var comment *models.Comment
+	err = r.audited(p.Context, viewer, string(policy.RestoreComment), id, "", func(ctx context.Context) (err error) {
+		comment, err = r.Storage.RestoreComment(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	comment.Replies = nil
+	return comment, nil
+}
+
+// VoteCommentResolver ставит или снимает голос пользователя за комментарий
+func (r *ResolverContext) VoteCommentResolver(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+	value, _ := p.Args["value"].(int)
+	if value < -1 || value > 1 {
+		return nil, badUserInput("value должен быть -1, 0 или 1")
+	}
+
+	user, err := r.userFor(p.Context, policy.VoteComment, p.Args["user"])
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := r.Storage.VoteComment(p.Context, id, user, value)
+	if err != nil {
+		return nil, err
+	}
+
+	comment.Replies = nil
+	return comment, nil
+}
+
+// ReactResolver ставит эмодзи-реакцию пользователя на комментарий (без emoji - снимает ее)
+func (r *ResolverContext) ReactResolver(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+
+	var emoji *string
+	if emojiArg, ok := p.Args["emoji"].(string); ok {
+		if err := validation.ValidateEmoji(emojiArg, "emoji"); err != nil {
+			return nil, err
+		}
+		emoji = &emojiArg
+	}
+
+	user, err := r.userFor(p.Context, policy.React, p.Args["user"])
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := r.Storage.ReactToComment(p.Context, id, user, emoji)
+	if err != nil {
+		return nil, err
+	}
+
+	comment.Replies = nil
+	return comment, nil
+}
+
+// ReactionCountsResolver возвращает число реакций на комментарий каждым эмодзи
+func (r *ResolverContext) ReactionCountsResolver(p graphql.ResolveParams) (interface{}, error) {
+	comment, ok := p.Source.(*models.Comment)
+	if !ok {
+		return nil, nil
+	}
+
+	// Реакции на удаленный комментарий не показываем, как и его текст
+	if comment.IsDeleted {
+		return []*models.ReactionCount{}, nil
+	}
+
+	// Внутри HTTP запроса реакции всех комментариев загружаются одним батчем
+	if loader := reactionCountsLoaderFromContext(p.Context, r.Storage); loader != nil {
+		load := loader.Load(comment.ID)
+		return func() (interface{}, error) {
+			counts, err := load()
+			if err != nil {
+				return nil, err
+			}
+			if counts == nil {
+				counts = []*models.ReactionCount{}
+			}
+			return counts, nil
+		}, nil
+	}
+
+	return r.Storage.GetReactionCounts(p.Context, comment.ID)
+}
+
+// ViewerReactionResolver возвращает голос и реакцию пользователя запроса на комментарий
+// (без аутентификации - пользователя из аргумента user)
+func (r *ResolverContext) ViewerReactionResolver(p graphql.ResolveParams) (interface{}, error) {
+	comment, ok := p.Source.(*models.Comment)
+	if !ok {
+		return nil, nil
+	}
+
+	user, _ := p.Args["user"].(string)
+	if viewer := auth.ViewerFromContext(p.Context); viewer != nil {
+		user = viewer.ID
+	}
+	if user == "" || comment.IsDeleted {
+		return nil, nil
+	}
+
+	if loader := reactionLoaderFromContext(p.Context, r.Storage, user); loader != nil {
+		load := loader.Load(comment.ID)
+		return func() (interface{}, error) {
+			reaction, err := load()
+			if err != nil {
+				return nil, err
+			}
+			if reaction == nil {
+				reaction = &models.Reaction{CommentID: comment.ID, UserID: user}
+			}
+			return reaction, nil
+		}, nil
+	}
+
+	return r.Storage.GetReaction(p.Context, comment.ID, user)
+}
+
+// CommentContentResolver возвращает текст комментария, скрывая текст удаленных
+func (r *ResolverContext) CommentContentResolver(p graphql.ResolveParams) (interface{}, error) {
+	comment, ok := p.Source.(*models.Comment)
+	if !ok {
+		return nil, nil
+	}
+
+	if comment.IsDeleted {
+		return deletedCommentContent, nil
+	}
+	if comment.IsHidden && !auth.ViewerFromContext(p.Context).HasRole(auth.RoleModerator) {
+		return hiddenCommentContent, nil
+	}
+	return comment.Content, nil
+}
+
 // CommentsResolver возвращает комментарии для поста (плоский список)
 func (r *ResolverContext) CommentsResolver(p graphql.ResolveParams) (interface{}, error) {
 	// p.Source содержит родительский объект (Post)
@@ -109,14 +600,88 @@ func (r *ResolverContext) CommentsResolver(p graphql.ResolveParams) (interface{}
 		return nil, nil
 	}
 
+	// Без maxDepth загружаем все комментарии поста,
+	// иначе только верхние уровни дерева
+	maxDepth, limited := p.Args["maxDepth"].(int)
+	if limited && maxDepth < 1 {
+		return nil, badUserInput("maxDepth должен быть не меньше 1")
+	}
+	order, _ := commentOrderFromArgs(p.Args)
+
+	if limited {
+		comments, err := r.Storage.GetCommentsUpToDepth(p.Context, post.ID, maxDepth)
+		if err != nil {
+			return nil, err
+		}
+
+		// Ответы на нижнем уровне не загружены - RepliesResolver подгрузит их по запросу
+		tree := r.buildCommentTree(comments, order)
+		markRepliesUnloaded(tree, 1, maxDepth)
+		return tree, nil
+	}
+
+	// Внутри HTTP запроса комментарии всех постов загружаются одним батчем
+	if loader := commentLoaderFromContext(p.Context, r.Storage); loader != nil {
+		load := loader.Load(post.ID)
+		return func() (interface{}, error) {
+			comments, err := load()
+			if err != nil {
+				return nil, err
+			}
+			return r.buildCommentTree(comments, order), nil
+		}, nil
+	}
+
 	// Получаем все комментарии для этого поста
-	comments, err := r.Storage.GetCommentsByPostID(post.ID)
+	comments, err := r.Storage.GetCommentsByPostID(p.Context, post.ID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Преобразуем плоский список в дерево
-	return r.buildCommentTree(comments), nil
+	return r.buildCommentTree(comments, order), nil
+}
+
+// CommentsConnectionResolver возвращает страницу комментариев поста
+// (плоский список в порядке создания) в виде Relay-соединения
+func (r *ResolverContext) CommentsConnectionResolver(p graphql.ResolveParams) (interface{}, error) {
+	post, ok := p.Source.(*models.Post)
+	if !ok {
+		return nil, nil
+	}
+
+	page, err := pageParamsFromArgs(p.Args, commentCursorPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	commentPage, err := r.Storage.GetCommentsPage(p.Context, post.ID, page)
+	if err != nil {
+		return nil, err
+	}
+
+	connection := &models.CommentConnection{
+		Edges: make([]*models.CommentEdge, 0, len(commentPage.Comments)),
+		PageInfo: &models.PageInfo{
+			HasNextPage:     commentPage.HasNextPage,
+			HasPreviousPage: commentPage.HasPreviousPage,
+		},
+		TotalCount: commentPage.TotalCount,
+	}
+	for _, comment := range commentPage.Comments {
+		// Узлы соединения - плоский список, ответы на них подгружаются лениво
+		comment.Replies = nil
+		connection.Edges = append(connection.Edges, &models.CommentEdge{
+			Cursor: encodeCursor(commentCursorPrefix, comment.ID),
+			Node:   comment,
+		})
+	}
+	if len(connection.Edges) > 0 {
+		connection.PageInfo.StartCursor = &connection.Edges[0].Cursor
+		connection.PageInfo.EndCursor = &connection.Edges[len(connection.Edges)-1].Cursor
+	}
+
+	return connection, nil
 }
 
 // RepliesResolver возвращает ответы на комментарий
@@ -127,11 +692,118 @@ func (r *ResolverContext) RepliesResolver(p graphql.ResolveParams) (interface{},
 		return nil, nil
 	}
 
-	return comment.Replies, nil
+	order, ordered := commentOrderFromArgs(p.Args)
+
+	// Ответы уже собраны в дерево и пагинация не запрошена
+	_, hasFirst := p.Args["first"]
+	_, hasAfter := p.Args["after"]
+	if comment.Replies != nil && !hasFirst && !hasAfter {
+		if !ordered {
+			return comment.Replies, nil
+		}
+		// Уровень мог быть упорядочен иначе, восстанавливаем порядок создания
+		replies := append([]*models.Comment(nil), comment.Replies...)
+		sort.SliceStable(replies, func(i, j int) bool {
+			return replies[i].CreatedAt.Before(replies[j].CreatedAt)
+		})
+		storage.SortComments(replies, order)
+		return replies, nil
+	}
+
+	// Иначе лениво загружаем один уровень ответов
+	page, err := pageParamsFromArgs(p.Args, commentCursorPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	replies, err := r.Storage.GetReplies(p.Context, comment.ID, order, page)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ответы следующего уровня тоже подгружаются лениво
+	for _, reply := range replies.Comments {
+		reply.Replies = nil
+	}
+
+	return replies.Comments, nil
+}
+
+// markRepliesUnloaded помечает ответы комментариев на глубине maxDepth как незагруженные (nil)
+func markRepliesUnloaded(comments []*models.Comment, depth, maxDepth int) {
+	for _, comment := range comments {
+		// Заглушка не занимает уровень: ее ответы загружены как корневые
+		if isOrphanPlaceholder(comment) {
+			markRepliesUnloaded(comment.Replies, depth, maxDepth)
+			continue
+		}
+		if depth >= maxDepth {
+			comment.Replies = nil
+			continue
+		}
+		markRepliesUnloaded(comment.Replies, depth+1, maxDepth)
+	}
 }
 
-// buildCommentTree преобразует плоский список комментариев в дерево
-func (r *ResolverContext) buildCommentTree(comments []*models.Comment) []*models.Comment {
+// CommentAddedSubscriber подписывает клиента на новые комментарии поста.
+// Возвращает канал, который закрывается при отключении клиента
+func (r *ResolverContext) CommentAddedSubscriber(p graphql.ResolveParams) (interface{}, error) {
+	postID, _ := p.Args["postId"].(string)
+
+	// Проверяем, что пост существует
+	if _, err := r.Storage.GetPost(p.Context, postID); err != nil {
+		return nil, err
+	}
+
+	sub := r.Hub.Subscribe(postID)
+	events := make(chan interface{})
+
+	go func() {
+		defer close(events)
+		defer sub.Close()
+
+		for {
+			select {
+			case <-p.Context.Done():
+				return
+			case comment, ok := <-sub.C():
+				if !ok {
+					// Hub отключил медленного подписчика
+					return
+				}
+				select {
+				case events <- comment:
+				case <-p.Context.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// CommentAddedResolver возвращает комментарий из события подписки
+func (r *ResolverContext) CommentAddedResolver(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source, nil
+}
+
+// buildCommentTree преобразует плоский список комментариев (в порядке создания)
+// в дерево и упорядочивает каждый его уровень по order
+func (r *ResolverContext) buildCommentTree(comments []*models.Comment, order storage.CommentOrder) []*models.Comment {
+	commentMap := linkCommentTree(comments)
+
+	// Корневые комментарии (не имеют родителя) в порядке исходного списка,
+	// комментарии без родителя обрабатываются по OrphanPolicy
+	rootComments := r.attachOrphans(comments, commentMap)
+
+	sortCommentTree(rootComments, order)
+	return rootComments
+}
+
+// linkCommentTree копирует комментарии и связывает ответы с родителями.
+// Возвращает мапу ID комментария -> комментарий
+func linkCommentTree(comments []*models.Comment) map[string]*models.Comment {
 	// Создаем мапу для быстрого доступа: ID комментария -> комментарий
 	commentMap := make(map[string]*models.Comment)
 
@@ -142,38 +814,40 @@ func (r *ResolverContext) buildCommentTree(comments []*models.Comment) []*models
 		commentMap[commentCopy.ID] = &commentCopy
 	}
 
-	// Собираем дерево
-	var rootComments []*models.Comment
-	for _, comment := range commentMap {
+	// Ответы добавляем в порядке исходного списка
+	for _, comment := range comments {
 		if comment.ParentID == nil {
-			// Корневой комментарий (не имеет родителя)
-			rootComments = append(rootComments, comment)
-		} else {
-			// Ответ на другой комментарий
-			parent, exists := commentMap[*comment.ParentID]
-			if exists {
-				parent.Replies = append(parent.Replies, comment)
-			}
+			continue
+		}
+		parent, exists := commentMap[*comment.ParentID]
+		if exists {
+			parent.Replies = append(parent.Replies, commentMap[comment.ID])
 		}
 	}
 
-	return rootComments
+	return commentMap
 }
 
-// generatePostID генерирует уникальный ID для поста
-func (r *ResolverContext) generatePostID() string {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+// authorFromArg возвращает автора из аргумента мутации или anonymousAuthor, если он не указан
+func (r *ResolverContext) authorFromArg(arg interface{}, path ...string) (string, error) {
+	author, _ := arg.(string)
+	author = strings.TrimSpace(author)
+	if author == "" {
+		return anonymousAuthor, nil
+	}
 
-	r.postCounter++
-	return "post_" + strconv.Itoa(r.postCounter)
+	if err := r.Limits.ValidateAuthor(author, path...); err != nil {
+		return "", err
+	}
+	return author, nil
 }
 
-// generateCommentID генерирует уникальный ID для комментария
-func (r *ResolverContext) generateCommentID() string {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+// generatePostID генерирует уникальный ID для поста
+func (r *ResolverContext) generatePostID(ctx context.Context) (string, error) {
+	return r.IDs.NewID(ctx, idgen.KindPost)
+}
 
-	r.commentCounter++
-	return "comment_" + strconv.Itoa(r.commentCounter)
+// generateCommentID генерирует уникальный ID для комментария
+func (r *ResolverContext) generateCommentID(ctx context.Context) (string, error) {
+	return r.IDs.NewID(ctx, idgen.KindComment)
 }
@@ -0,0 +1,52 @@
+package gql
+
+import (
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// DateTime - скаляр даты и времени в формате RFC 3339 (всегда в UTC)
+var DateTime = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "DateTime",
+	Description: "Дата и время в формате RFC 3339, например 2024-05-01T12:30:00Z",
+	Serialize: func(value interface{}) interface{} {
+		switch v := value.(type) {
+		case time.Time:
+			if v.IsZero() {
+				return nil
+			}
+			return v.UTC().Format(time.RFC3339Nano)
+		case *time.Time:
+			if v == nil || v.IsZero() {
+				return nil
+			}
+			return v.UTC().Format(time.RFC3339Nano)
+		default:
+			return nil
+		}
+	},
+	ParseValue: func(value interface{}) interface{} {
+		s, ok := value.(string)
+		if !ok {
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil
+		}
+		return t
+	},
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		s, ok := valueAST.(*ast.StringValue)
+		if !ok {
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, s.Value)
+		if err != nil {
+			return nil
+		}
+		return t
+	},
+})
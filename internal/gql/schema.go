@@ -1,14 +1,69 @@
 package gql
 
 import (
+	"graphql-comments/internal/idgen"
+	"graphql-comments/internal/moderation"
+	"graphql-comments/internal/policy"
+	"graphql-comments/internal/pubsub"
+	"graphql-comments/internal/ratelimit"
 	"graphql-comments/internal/storage"
+	"graphql-comments/internal/validation"
 
 	"github.com/graphql-go/graphql"
 )
 
-func BuildSchema(store storage.Storage) (*graphql.Schema, error) {
+// Config - зависимости GraphQL схемы
+type Config struct {
+	Storage storage.Storage
+	// Hub - шина событий для подписок, по умолчанию создается своя
+	Hub *pubsub.Hub
+	// IDs - генератор ID, по умолчанию счетчики в памяти
+	IDs idgen.Generator
+	// Limits - ограничения на содержимое, незаданные поля - из validation.DefaultLimits
+	Limits validation.Limits
+	// SoftDelete - deleteComment оставляет заглушку вместо удаления ветки ответов
+	SoftDelete bool
+	// OrphanPolicy - что делать с комментариями без родителя, по умолчанию OrphanDrop
+	OrphanPolicy OrphanPolicy
+	// RequireAuth - мутации только для аутентифицированных пользователей (auth.Middleware)
+	RequireAuth bool
+	// Policy - проверка прав на мутации, по умолчанию policy.RoleBased с RequireAuth
+	Policy policy.Policy
+	// ContentFilters - фильтры новых комментариев и правок: отмеченные ждут проверки модератором.
+	// Без фильтров комментарии публикуются сразу
+	ContentFilters []moderation.ContentFilter
+	// RateLimiter - лимиты на создание постов и комментариев (nil - без ограничений).
+	// Его же Middleware ограничивает HTTP запросы
+	RateLimiter *ratelimit.Limiter
+}
+
+func BuildSchema(cfg Config) (*graphql.Schema, error) {
+	// Без внешнего хаба подписки работают через собственный
+	if cfg.Hub == nil {
+		cfg.Hub = pubsub.NewHub(pubsub.DefaultBufferSize)
+	}
+	if cfg.IDs == nil {
+		cfg.IDs = idgen.NewCounter()
+	}
+	// Незаданные поля берутся по умолчанию, чтобы частично заполненные
+	// ограничения не отклоняли все посты и комментарии
+	cfg.Limits = cfg.Limits.WithDefaults()
+	if cfg.Policy == nil {
+		cfg.Policy = policy.RoleBased{RequireAuth: cfg.RequireAuth}
+	}
+
 	resolverContext := &ResolverContext{
-		Storage: store,
+		Storage:      cfg.Storage,
+		Hub:          cfg.Hub,
+		IDs:          cfg.IDs,
+		Limits:       cfg.Limits,
+		SoftDelete:   cfg.SoftDelete,
+		OrphanPolicy: cfg.OrphanPolicy,
+		Policy:       cfg.Policy,
+		Limiter:      cfg.RateLimiter,
+	}
+	if len(cfg.ContentFilters) > 0 {
+		resolverContext.Moderation = moderation.NewPipeline(cfg.ContentFilters...)
 	}
 
 	// Comment тип
@@ -18,16 +73,117 @@ func BuildSchema(store storage.Storage) (*graphql.Schema, error) {
 			"id":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
 			"postId":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
 			"parentId": &graphql.Field{Type: graphql.String},
+			"content": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.String),
+				Resolve: resolverContext.CommentContentResolver,
+			},
+			"author":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"createdAt": &graphql.Field{Type: graphql.NewNonNull(DateTime)},
+			"updatedAt": &graphql.Field{Type: graphql.NewNonNull(DateTime)},
+			"version":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"isDeleted": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"deletedAt": &graphql.Field{Type: DateTime},
+			"score":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			// replyCount - число прямых ответов, включая заглушки удаленных
+			"replyCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			// isHidden - комментарий скрыт модератором (текст видят только модераторы)
+			"isHidden": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			// isLocked - ветка закрыта для новых ответов
+			"isLocked": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"status":   &graphql.Field{Type: graphql.NewNonNull(CommentStatusEnum)},
+			// moderationReason - почему фильтры отправили комментарий на проверку (только модераторам)
+			"moderationReason": &graphql.Field{
+				Type:    graphql.String,
+				Resolve: resolverContext.ModerationReasonResolver,
+			},
+		},
+	})
+
+	// CommentRevision тип - предыдущая версия текста комментария
+	commentRevisionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CommentRevision",
+		Fields: graphql.Fields{
+			"version":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
 			"content":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"editedAt": &graphql.Field{Type: graphql.NewNonNull(DateTime)},
+		},
+	})
+	commentType.AddFieldConfig("revisions", &graphql.Field{
+		Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(commentRevisionType))),
+		Resolve: resolverContext.RevisionsResolver,
+	})
+
+	// Голоса и реакции пользователей
+	reactionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Reaction",
+		Fields: graphql.Fields{
+			"value":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"emoji":     &graphql.Field{Type: graphql.String},
+			"updatedAt": &graphql.Field{Type: DateTime},
 		},
 	})
+	reactionCountType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ReactionCount",
+		Fields: graphql.Fields{
+			"emoji": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"count": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+	commentType.AddFieldConfig("reactionCounts", &graphql.Field{
+		Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(reactionCountType))),
+		Resolve: resolverContext.ReactionCountsResolver,
+	})
+	// С аутентификацией реакция берется только у пользователя запроса,
+	// чтобы нельзя было подсмотреть реакции других пользователей
+	viewerReactionArgs := graphql.FieldConfigArgument{}
+	if !cfg.RequireAuth {
+		viewerReactionArgs["user"] = &graphql.ArgumentConfig{Type: graphql.String}
+	}
+	commentType.AddFieldConfig("viewerReaction", &graphql.Field{
+		Type:    reactionType,
+		Args:    viewerReactionArgs,
+		Resolve: resolverContext.ViewerReactionResolver,
+	})
 
 	// Добавляем replies рекурсивно
 	commentType.AddFieldConfig("replies", &graphql.Field{
-		Type:    graphql.NewList(commentType),
+		Type: graphql.NewList(commentType),
+		Args: graphql.FieldConfigArgument{
+			"first":   &graphql.ArgumentConfig{Type: graphql.Int},
+			"after":   &graphql.ArgumentConfig{Type: graphql.String},
+			"orderBy": &graphql.ArgumentConfig{Type: CommentOrderInput},
+		},
 		Resolve: resolverContext.RepliesResolver,
 	})
 
+	// PageInfo тип - общий для всех соединений
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage":     &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"hasPreviousPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"startCursor":     &graphql.Field{Type: graphql.String},
+			"endCursor":       &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	// CommentConnection тип
+	commentEdgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CommentEdge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"node":   &graphql.Field{Type: graphql.NewNonNull(commentType)},
+		},
+	})
+	commentConnectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CommentConnection",
+		Fields: graphql.Fields{
+			"edges":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(commentEdgeType)))},
+			"pageInfo":   &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+			"totalCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+
 	// Post тип
 	postType := graphql.NewObject(graphql.ObjectConfig{
 		Name: "Post",
@@ -35,10 +191,77 @@ func BuildSchema(store storage.Storage) (*graphql.Schema, error) {
 			"id":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
 			"title":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
 			"content": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"allowComments": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.Boolean),
+				Resolve: resolverContext.AllowCommentsResolver,
+			},
+			"author":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"createdAt": &graphql.Field{Type: graphql.NewNonNull(DateTime)},
+			"updatedAt": &graphql.Field{Type: graphql.NewNonNull(DateTime)},
+			"version":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
 			"comments": &graphql.Field{
-				Type:    graphql.NewList(commentType),
+				Type: graphql.NewList(commentType),
+				Args: graphql.FieldConfigArgument{
+					"maxDepth": &graphql.ArgumentConfig{Type: graphql.Int},
+					"orderBy":  &graphql.ArgumentConfig{Type: CommentOrderInput},
+				},
 				Resolve: resolverContext.CommentsResolver,
 			},
+			"commentsConnection": &graphql.Field{
+				Type:    graphql.NewNonNull(commentConnectionType),
+				Args:    connectionArgs(),
+				Resolve: resolverContext.CommentsConnectionResolver,
+			},
+		},
+	})
+
+	// PostConnection тип
+	postEdgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PostEdge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"node":   &graphql.Field{Type: graphql.NewNonNull(postType)},
+		},
+	})
+	postConnectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PostConnection",
+		Fields: graphql.Fields{
+			"edges":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(postEdgeType)))},
+			"pageInfo":   &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+			"totalCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+
+	// CommentThread тип - комментарий с поддеревом ответов и цепочкой предков
+	commentThreadType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "CommentThread",
+		Fields: graphql.Fields{
+			"comment":   &graphql.Field{Type: graphql.NewNonNull(commentType)},
+			"ancestors": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(commentType)))},
+		},
+	})
+
+	// Viewer тип - аутентифицированный пользователь запроса
+	viewerType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Viewer",
+		Fields: graphql.Fields{
+			"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"name":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"authMethod": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"role":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	// AuditEntry тип - запись журнала действий модераторов
+	auditEntryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "AuditEntry",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"actorId":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"action":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"targetId":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"details":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"createdAt": &graphql.Field{Type: graphql.NewNonNull(DateTime)},
 		},
 	})
 
@@ -46,10 +269,59 @@ func BuildSchema(store storage.Storage) (*graphql.Schema, error) {
 	rootQuery := graphql.NewObject(graphql.ObjectConfig{
 		Name: "Query",
 		Fields: graphql.Fields{
+			"me": &graphql.Field{
+				Type:    viewerType,
+				Resolve: resolverContext.MeResolver,
+			},
 			"posts": &graphql.Field{
 				Type:    graphql.NewList(postType),
 				Resolve: resolverContext.PostsResolver,
 			},
+			"postsConnection": &graphql.Field{
+				Type:    graphql.NewNonNull(postConnectionType),
+				Args:    connectionArgs(),
+				Resolve: resolverContext.PostsConnectionResolver,
+			},
+			"post": &graphql.Field{
+				Type: postType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: resolverContext.PostResolver,
+			},
+			"comment": &graphql.Field{
+				Type: commentType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: resolverContext.CommentResolver,
+			},
+			"commentThread": &graphql.Field{
+				Type: commentThreadType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					// Сколько ближайших предков вернуть (по умолчанию - все)
+					"ancestors": &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: resolverContext.CommentThreadResolver,
+			},
+			// moderationQueue - комментарии, ожидающие проверки (старые первыми), только для модераторов
+			"moderationQueue": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(commentType))),
+				Args: graphql.FieldConfigArgument{
+					"postId": &graphql.ArgumentConfig{Type: graphql.ID},
+					"first":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 50},
+				},
+				Resolve: resolverContext.ModerationQueueResolver,
+			},
+			// auditLog - журнал модерации, только для администраторов
+			"auditLog": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(auditEntryType))),
+				Args: graphql.FieldConfigArgument{
+					"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 50},
+				},
+				Resolve: resolverContext.AuditLogResolver,
+			},
 		},
 	})
 
@@ -62,9 +334,22 @@ func BuildSchema(store storage.Storage) (*graphql.Schema, error) {
 				Args: graphql.FieldConfigArgument{
 					"title":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
 					"content": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"allowComments": &graphql.ArgumentConfig{
+						Type:         graphql.Boolean,
+						DefaultValue: true,
+					},
+					"author": &graphql.ArgumentConfig{Type: graphql.String},
 				},
 				Resolve: resolverContext.CreatePostResolver,
 			},
+			"setAllowComments": &graphql.Field{
+				Type: postType,
+				Args: graphql.FieldConfigArgument{
+					"postId":        &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"allowComments": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Boolean)},
+				},
+				Resolve: resolverContext.SetAllowCommentsResolver,
+			},
 			"createComment": &graphql.Field{
 				Type: commentType,
 				Args: graphql.FieldConfigArgument{
@@ -75,19 +360,158 @@ func BuildSchema(store storage.Storage) (*graphql.Schema, error) {
 								"postId":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
 								"parentId": &graphql.InputObjectFieldConfig{Type: graphql.String},
 								"content":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+								"author":   &graphql.InputObjectFieldConfig{Type: graphql.String},
 							},
 						}),
 					},
 				},
 				Resolve: resolverContext.CreateCommentResolver,
 			},
+			"updatePost": &graphql.Field{
+				Type: postType,
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.NewInputObject(graphql.InputObjectConfig{
+							Name: "UpdatePostInput",
+							Fields: graphql.InputObjectConfigFieldMap{
+								"id":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.ID)},
+								"title":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+								"content": &graphql.InputObjectFieldConfig{Type: graphql.String},
+								"version": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+							},
+						})),
+					},
+				},
+				Resolve: resolverContext.UpdatePostResolver,
+			},
+			"updateComment": &graphql.Field{
+				Type: commentType,
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.NewInputObject(graphql.InputObjectConfig{
+							Name: "UpdateCommentInput",
+							Fields: graphql.InputObjectConfigFieldMap{
+								"id":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.ID)},
+								"content": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+								"version": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+							},
+						})),
+					},
+				},
+				Resolve: resolverContext.UpdateCommentResolver,
+			},
+			"deletePost": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: resolverContext.DeletePostResolver,
+			},
+			"deleteComment": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: resolverContext.DeleteCommentResolver,
+			},
+			"voteComment": &graphql.Field{
+				Type: commentType,
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"value": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"user":  &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: resolverContext.VoteCommentResolver,
+			},
+			"react": &graphql.Field{
+				Type: commentType,
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"emoji": &graphql.ArgumentConfig{Type: graphql.String},
+					"user":  &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: resolverContext.ReactResolver,
+			},
+			"restoreComment": &graphql.Field{
+				Type: commentType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: resolverContext.RestoreCommentResolver,
+			},
+			// Мутации модераторов
+			"hideComment": &graphql.Field{
+				Type: commentType,
+				Args: graphql.FieldConfigArgument{
+					"id":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"hidden": &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: true},
+					"reason": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: resolverContext.HideCommentResolver,
+			},
+			"lockThread": &graphql.Field{
+				Type: commentType,
+				Args: graphql.FieldConfigArgument{
+					"id":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"locked": &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: true},
+					"reason": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: resolverContext.LockThreadResolver,
+			},
+			"banUserFromPost": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"postId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"userId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"reason": &graphql.ArgumentConfig{Type: graphql.String},
+					// banned: false снимает запрет
+					"banned": &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: true},
+				},
+				Resolve: resolverContext.BanUserFromPostResolver,
+			},
+			"approveComment": &graphql.Field{
+				Type: commentType,
+				Args: graphql.FieldConfigArgument{
+					"id":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"reason": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: resolverContext.ApproveCommentResolver,
+			},
+			"rejectComment": &graphql.Field{
+				Type: commentType,
+				Args: graphql.FieldConfigArgument{
+					"id":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"reason": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: resolverContext.RejectCommentResolver,
+			},
+		},
+	})
+
+	// Subscription
+	rootSubscription := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"commentAdded": &graphql.Field{
+				Type: graphql.NewNonNull(commentType),
+				Args: graphql.FieldConfigArgument{
+					"postId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Subscribe: resolverContext.CommentAddedSubscriber,
+				Resolve:   resolverContext.CommentAddedResolver,
+			},
 		},
 	})
 
 	schema, err := graphql.NewSchema(graphql.SchemaConfig{
-		Query:    rootQuery,
-		Mutation: rootMutation,
+		Query:        rootQuery,
+		Mutation:     rootMutation,
+		Subscription: rootSubscription,
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	return &schema, err
+	wrapResolvers(&schema)
+	return &schema, nil
 }
@@ -0,0 +1,112 @@
+package gql
+
+import (
+	"context"
+
+	"graphql-comments/internal/auth"
+	"graphql-comments/internal/models"
+	"graphql-comments/internal/policy"
+
+	"github.com/graphql-go/graphql"
+)
+
+// authorize спрашивает политику, может ли пользователь запроса выполнить операцию
+// над записью автора ownerID. Возвращает пользователя (nil для анонимного запроса)
+func (r *ResolverContext) authorize(ctx context.Context, action policy.Action, ownerID string) (*auth.Viewer, error) {
+	viewer := auth.ViewerFromContext(ctx)
+	if err := r.Policy.Authorize(viewer, action, policy.Resource{OwnerID: ownerID}); err != nil {
+		return nil, err
+	}
+	return viewer, nil
+}
+
+// authorizePost проверяет права на операцию над постом.
+// Второе значение true, если пользователь действует не над своей записью (модерация)
+func (r *ResolverContext) authorizePost(ctx context.Context, action policy.Action, postID string) (*auth.Viewer, bool, error) {
+	if auth.ViewerFromContext(ctx) == nil {
+		_, err := r.authorize(ctx, action, "")
+		return nil, false, err
+	}
+
+	post, err := r.Storage.GetPost(ctx, postID)
+	if err != nil {
+		return nil, false, err
+	}
+	viewer, err := r.authorize(ctx, action, post.Author)
+	if err != nil {
+		return nil, false, err
+	}
+	return viewer, viewer.ID != post.Author, nil
+}
+
+// authorizeComment проверяет права на операцию над комментарием, как authorizePost
+func (r *ResolverContext) authorizeComment(ctx context.Context, action policy.Action, commentID string) (*auth.Viewer, bool, error) {
+	if auth.ViewerFromContext(ctx) == nil {
+		_, err := r.authorize(ctx, action, "")
+		return nil, false, err
+	}
+
+	comment, err := r.Storage.GetComment(ctx, commentID)
+	if err != nil {
+		return nil, false, err
+	}
+	viewer, err := r.authorize(ctx, action, comment.Author)
+	if err != nil {
+		return nil, false, err
+	}
+	return viewer, viewer.ID != comment.Author, nil
+}
+
+// authorFor проверяет право создать запись и возвращает ее автора:
+// ID пользователя запроса, а для анонимного запроса - аргумент author (или anonymousAuthor)
+func (r *ResolverContext) authorFor(ctx context.Context, action policy.Action, arg interface{}, path ...string) (string, error) {
+	viewer, err := r.authorize(ctx, action, "")
+	if err != nil {
+		return "", err
+	}
+	if viewer != nil {
+		return viewer.ID, nil
+	}
+	return r.authorFromArg(arg, path...)
+}
+
+// userFor проверяет право голосовать или реагировать и возвращает ID пользователя:
+// пользователя запроса, а для анонимного запроса - аргумент user
+func (r *ResolverContext) userFor(ctx context.Context, action policy.Action, arg interface{}) (string, error) {
+	viewer, err := r.authorize(ctx, action, "")
+	if err != nil {
+		return "", err
+	}
+	if viewer != nil {
+		return viewer.ID, nil
+	}
+
+	user, _ := arg.(string)
+	if user == "" {
+		return "", badUserInput("для анонимного запроса нужен аргумент user")
+	}
+	if err := r.Limits.ValidateAuthor(user, "user"); err != nil {
+		return "", err
+	}
+	return user, nil
+}
+
+// audited выполняет действие модератора fn вместе с записью в журнал: если журнал
+// не записан, действие откатывается и мутация возвращает ошибку.
+// Без пользователя (viewer == nil) записывать некого - fn выполняется как есть
+func (r *ResolverContext) audited(ctx context.Context, viewer *auth.Viewer, action, targetID, details string, fn func(ctx context.Context) error) error {
+	if viewer == nil {
+		return fn(ctx)
+	}
+	entry := &models.AuditEntry{ActorID: viewer.ID, Action: action, TargetID: targetID, Details: details}
+	return r.Storage.WithAudit(ctx, entry, fn)
+}
+
+// MeResolver возвращает пользователя запроса или null для анонимного
+func (r *ResolverContext) MeResolver(p graphql.ResolveParams) (interface{}, error) {
+	viewer := auth.ViewerFromContext(p.Context)
+	if viewer == nil {
+		return nil, nil
+	}
+	return viewer, nil
+}
@@ -0,0 +1,240 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"graphql-comments/internal/auth"
+	"graphql-comments/internal/models"
+	"graphql-comments/internal/storage"
+
+	"github.com/graphql-go/graphql"
+)
+
+func TestViewer_AuthorAndOwnership(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Контент", Author: "alice"})
+
+	schema, err := BuildSchema(Config{Storage: store, RequireAuth: true})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+	as := func(viewer *auth.Viewer, request string) *graphql.Result {
+		requestCtx := context.Background()
+		if viewer != nil {
+			requestCtx = auth.WithViewer(requestCtx, viewer)
+		}
+		return graphql.Do(graphql.Params{Schema: *schema, RequestString: request, Context: requestCtx})
+	}
+	alice := &auth.Viewer{ID: "alice", Name: "Алиса", Method: auth.MethodJWT}
+	bob := &auth.Viewer{ID: "bob", Name: "bob", Method: auth.MethodAPIKey}
+
+	// 1. Анонимный запрос не может писать
+	result := as(nil, `mutation { createComment(input: {postId: "post_1", content: "Привет"}) { id } }`)
+	if len(result.Errors) == 0 || result.Errors[0].Extensions["code"] != CodeUnauthenticated {
+		t.Fatalf("Ожидали UNAUTHENTICATED, получили %v", result.Errors)
+	}
+
+	// 2. Автор комментария - пользователь запроса, аргумент author игнорируется
+	result = as(alice, `mutation { createComment(input: {postId: "post_1", content: "Привет", author: "mallory"}) { id author } }`)
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+	created := result.Data.(map[string]interface{})["createComment"].(map[string]interface{})
+	if created["author"] != "alice" {
+		t.Errorf("Ожидали автора alice, получили %v", created["author"])
+	}
+	commentID := created["id"].(string)
+
+	// 3. Чужой комментарий и чужой пост изменить нельзя
+	for _, request := range []string{
+		`mutation { updateComment(input: {id: "` + commentID + `", content: "Взлом", version: 1}) { id } }`,
+		`mutation { deleteComment(id: "` + commentID + `") }`,
+		`mutation { deletePost(id: "post_1") }`,
+	} {
+		result = as(bob, request)
+		if len(result.Errors) == 0 || result.Errors[0].Extensions["code"] != CodeForbidden {
+			t.Errorf("Ожидали FORBIDDEN для %s, получили %v", request, result.Errors)
+		}
+	}
+
+	// 4. Автор может удалить свой комментарий
+	result = as(alice, `mutation { deleteComment(id: "`+commentID+`") }`)
+	if len(result.Errors) > 0 {
+		t.Errorf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+
+	// 5. me возвращает пользователя запроса, для анонима - null
+	result = as(alice, `{ me { id name authMethod } }`)
+	me := result.Data.(map[string]interface{})["me"].(map[string]interface{})
+	if me["id"] != "alice" || me["name"] != "Алиса" || me["authMethod"] != auth.MethodJWT {
+		t.Errorf("Неожиданный me: %v", me)
+	}
+	result = as(nil, `{ me { id } }`)
+	if result.Data.(map[string]interface{})["me"] != nil {
+		t.Errorf("Ожидали me = null для анонима, получили %v", result.Data)
+	}
+}
+
+func TestViewer_ModeratorActions(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Контент", Author: "alice"})
+	store.CreateComment(ctx, &models.Comment{ID: "rude_1", PostID: "post_1", Content: "Грубость", Author: "bob"})
+
+	schema, err := BuildSchema(Config{Storage: store, RequireAuth: true})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+	as := func(viewer *auth.Viewer, request string) *graphql.Result {
+		return graphql.Do(graphql.Params{Schema: *schema, RequestString: request, Context: auth.WithViewer(context.Background(), viewer)})
+	}
+	bob := &auth.Viewer{ID: "bob", Role: auth.RoleUser}
+	moder := &auth.Viewer{ID: "moder", Role: auth.RoleModerator}
+	admin := &auth.Viewer{ID: "root", Role: auth.RoleAdmin}
+
+	// 1. Обычный пользователь не может модерировать
+	for _, request := range []string{
+		`mutation { hideComment(id: "rude_1") { id } }`,
+		`mutation { lockThread(id: "rude_1") { id } }`,
+		`mutation { banUserFromPost(postId: "post_1", userId: "bob") }`,
+		`{ auditLog { id } }`,
+	} {
+		result := as(bob, request)
+		if len(result.Errors) == 0 || result.Errors[0].Extensions["code"] != CodeForbidden {
+			t.Errorf("Ожидали FORBIDDEN для %s, получили %v", request, result.Errors)
+		}
+	}
+
+	// 2. Скрытый текст видит только модератор
+	result := as(moder, `mutation { hideComment(id: "rude_1", reason: "грубость") { isHidden content } }`)
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+	hidden := result.Data.(map[string]interface{})["hideComment"].(map[string]interface{})
+	if hidden["isHidden"] != true || hidden["content"] != "Грубость" {
+		t.Errorf("Модератор должен видеть скрытый текст, получили %v", hidden)
+	}
+	result = as(bob, `{ comment(id: "rude_1") { content } }`)
+	if got := result.Data.(map[string]interface{})["comment"].(map[string]interface{})["content"]; got != hiddenCommentContent {
+		t.Errorf("Ожидали заглушку скрытого комментария, получили %v", got)
+	}
+
+	// 3. В закрытую ветку и забаненному пользователю писать нельзя
+	as(moder, `mutation { lockThread(id: "rude_1") { isLocked } }`)
+	result = as(bob, `mutation { createComment(input: {postId: "post_1", parentId: "rude_1", content: "Ответ"}) { id } }`)
+	if len(result.Errors) == 0 || result.Errors[0].Extensions["code"] != CodeThreadLocked {
+		t.Errorf("Ожидали THREAD_LOCKED, получили %v", result.Errors)
+	}
+	as(moder, `mutation { banUserFromPost(postId: "post_1", userId: "bob", reason: "спам") }`)
+	result = as(bob, `mutation { createComment(input: {postId: "post_1", content: "Еще"}) { id } }`)
+	if len(result.Errors) == 0 || result.Errors[0].Extensions["code"] != CodeUserBanned {
+		t.Errorf("Ожидали USER_BANNED, получили %v", result.Errors)
+	}
+
+	// 4. Модератор удаляет чужой комментарий, но не чужой пост
+	result = as(moder, `mutation { deletePost(id: "post_1") }`)
+	if len(result.Errors) == 0 || result.Errors[0].Extensions["code"] != CodeForbidden {
+		t.Errorf("Ожидали FORBIDDEN на удаление поста модератором, получили %v", result.Errors)
+	}
+	result = as(moder, `mutation { deleteComment(id: "rude_1") }`)
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+
+	// 5. Администратор видит все действия модератора, новые первыми
+	result = as(admin, `{ auditLog(limit: 10) { actorId action targetId details } }`)
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+	}
+	entries := result.Data.(map[string]interface{})["auditLog"].([]interface{})
+	var actions []string
+	for _, entry := range entries {
+		entry := entry.(map[string]interface{})
+		if entry["actorId"] != "moder" {
+			t.Errorf("Ожидали действие модератора, получили %v", entry)
+		}
+		actions = append(actions, entry["action"].(string))
+	}
+	want := []string{"deleteComment", "banUserFromPost", "lockThread", "hideComment"}
+	if strings.Join(actions, ",") != strings.Join(want, ",") {
+		t.Errorf("Ожидали журнал %v, получили %v", want, actions)
+	}
+
+	result = as(admin, `{ auditLog(limit: 0) { id } }`)
+	if len(result.Errors) == 0 || result.Errors[0].Extensions["code"] != CodeBadUserInput {
+		t.Errorf("Ожидали BAD_USER_INPUT для limit 0, получили %v", result.Errors)
+	}
+}
+
+// auditFailingStorage - хранилище, в котором журнал модерации недоступен
+type auditFailingStorage struct {
+	storage.Storage
+}
+
+func (s auditFailingStorage) WithAudit(ctx context.Context, entry *models.AuditEntry, fn func(ctx context.Context) error) error {
+	return errors.New("журнал недоступен")
+}
+
+func TestViewer_ModeratorActionFailsWithoutAudit(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Контент"})
+	store.CreateComment(ctx, &models.Comment{ID: "rude_1", PostID: "post_1", Content: "Грубость", Author: "bob"})
+
+	schema, err := BuildSchema(Config{Storage: auditFailingStorage{store}})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+	moder := &auth.Viewer{ID: "moder", Role: auth.RoleModerator}
+	result := graphql.Do(graphql.Params{
+		Schema:        *schema,
+		RequestString: `mutation { hideComment(id: "rude_1") { id } }`,
+		Context:       auth.WithViewer(ctx, moder),
+	})
+	if len(result.Errors) == 0 {
+		t.Fatal("Ожидали ошибку мутации без записи в журнал")
+	}
+	if comment, err := store.GetComment(ctx, "rude_1"); err != nil || comment.IsHidden {
+		t.Errorf("Комментарий не должен скрываться без записи в журнал: %+v, %v", comment, err)
+	}
+}
+
+func TestRevisions_HiddenComment(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Контент", Author: "alice"})
+	store.CreateComment(ctx, &models.Comment{ID: "rude_1", PostID: "post_1", Content: "Грубость", Author: "bob"})
+	store.UpdateComment(ctx, &models.UpdateCommentInput{ID: "rude_1", Content: "Еще грубее", Version: 1})
+	store.HideComment(ctx, "rude_1", true)
+
+	schema, err := BuildSchema(Config{Storage: store, RequireAuth: true})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+	revisionsAs := func(viewer *auth.Viewer) []interface{} {
+		result := graphql.Do(graphql.Params{
+			Schema:        *schema,
+			RequestString: `{ comment(id: "rude_1") { revisions { content } } }`,
+			Context:       auth.WithViewer(context.Background(), viewer),
+		})
+		if len(result.Errors) > 0 {
+			t.Fatalf("Ошибки выполнения запроса: %v", result.Errors)
+		}
+		return result.Data.(map[string]interface{})["comment"].(map[string]interface{})["revisions"].([]interface{})
+	}
+
+	// История скрытого комментария, как и его текст, видна только модераторам
+	for _, viewer := range []*auth.Viewer{nil, {ID: "alice", Role: auth.RoleUser}, {ID: "bob", Role: auth.RoleUser}} {
+		if revisions := revisionsAs(viewer); len(revisions) != 0 {
+			t.Errorf("Ожидали пустую историю для %v, получили %v", viewer, revisions)
+		}
+	}
+	revisions := revisionsAs(&auth.Viewer{ID: "moder", Role: auth.RoleModerator})
+	if len(revisions) != 1 || revisions[0].(map[string]interface{})["content"] != "Грубость" {
+		t.Errorf("Ожидали историю для модератора, получили %v", revisions)
+	}
+}
@@ -0,0 +1,387 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"graphql-comments/internal/persisted"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// wsProtocol - подпротокол GraphQL over WebSocket (graphql-transport-ws)
+const wsProtocol = "graphql-transport-ws"
+
+const (
+	// wsConnectionInitTimeout - сколько ждем connection_init после подключения
+	wsConnectionInitTimeout = 10 * time.Second
+	// wsWriteTimeout - таймаут записи одного сообщения клиенту
+	wsWriteTimeout = 10 * time.Second
+)
+
+// Типы сообщений протокола
+const (
+	wsMsgConnectionInit = "connection_init"
+	wsMsgConnectionAck  = "connection_ack"
+	wsMsgPing           = "ping"
+	wsMsgPong           = "pong"
+	wsMsgSubscribe      = "subscribe"
+	wsMsgNext           = "next"
+	wsMsgError          = "error"
+	wsMsgComplete       = "complete"
+)
+
+// Коды закрытия соединения из спецификации протокола
+const (
+	wsCloseBadRequest       = 4400
+	wsCloseUnauthorized     = 4401
+	wsCloseInitTimeout      = 4408
+	wsCloseSubscriberExists = 4409
+	wsCloseTooManyInit      = 4429
+)
+
+// wsMessage - сообщение протокола graphql-transport-ws
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsSubscribePayload - полезная нагрузка сообщения subscribe
+type wsSubscribePayload struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+	Extensions    requestExtensions      `json:"extensions"`
+}
+
+// NewWebSocketHandler создает обработчик GraphQL поверх WebSocket
+// по протоколу graphql-transport-ws
+func NewWebSocketHandler(schema *graphql.Schema, cfg HandlerConfig) http.Handler {
+	upgrader := websocket.Upgrader{
+		Subprotocols: []string{wsProtocol},
+		CheckOrigin:  checkOrigin(cfg.AllowedOrigins),
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// Upgrader уже ответил клиенту ошибкой
+			return
+		}
+
+		c := &wsConnection{
+			conn:       conn,
+			schema:     schema,
+			limits:     cfg.QueryLimits,
+			persisted:  cfg.PersistedQueries,
+			ctx:        r.Context(),
+			operations: make(map[string]*wsOperation),
+		}
+		if conn.Subprotocol() != wsProtocol {
+			c.close(websocket.CloseProtocolError, "Subprotocol not acceptable")
+			return
+		}
+
+		c.serve()
+	})
+}
+
+// checkOrigin разрешает соединение без Origin (не браузер), со страницы с адреса сервера
+// или из allowed. Браузер отправляет cookie на WebSocket с любой страницы,
+// поэтому без проверки чужой сайт подписывался бы от имени пользователя
+func checkOrigin(allowed []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+		for _, candidate := range allowed {
+			if strings.EqualFold(strings.TrimSuffix(candidate, "/"), origin) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// wsConnection - состояние одного WebSocket соединения
+type wsConnection struct {
+	conn      *websocket.Conn
+	schema    *graphql.Schema
+	limits    QueryLimits
+	persisted *persisted.Queries
+	ctx       context.Context
+
+	writeMu sync.Mutex // gorilla/websocket допускает только одного писателя
+
+	mu           sync.Mutex
+	initReceived bool
+	acknowledged bool
+	operations   map[string]*wsOperation
+}
+
+// wsOperation - выполняющаяся операция клиента
+type wsOperation struct {
+	cancel context.CancelFunc
+}
+
+// serve читает сообщения клиента до разрыва соединения
+func (c *wsConnection) serve() {
+	defer c.conn.Close()
+	defer c.cancelAll()
+
+	// Клиент обязан прислать connection_init в течение таймаута
+	initTimer := time.AfterFunc(wsConnectionInitTimeout, func() {
+		c.mu.Lock()
+		acknowledged := c.acknowledged
+		c.mu.Unlock()
+		if !acknowledged {
+			c.close(wsCloseInitTimeout, "Connection initialisation timeout")
+		}
+	})
+	defer initTimer.Stop()
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var msg wsMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.close(wsCloseBadRequest, "Invalid message received")
+			return
+		}
+
+		if !c.handleMessage(msg) {
+			return
+		}
+	}
+}
+
+// handleMessage обрабатывает одно сообщение клиента.
+// Возвращает false, если соединение нужно закрыть
+func (c *wsConnection) handleMessage(msg wsMessage) bool {
+	switch msg.Type {
+	case wsMsgConnectionInit:
+		c.mu.Lock()
+		if c.initReceived {
+			c.mu.Unlock()
+			c.close(wsCloseTooManyInit, "Too many initialisation requests")
+			return false
+		}
+		c.initReceived = true
+		c.acknowledged = true
+		c.mu.Unlock()
+		c.send(wsMessage{Type: wsMsgConnectionAck})
+
+	case wsMsgPing:
+		c.send(wsMessage{Type: wsMsgPong})
+
+	case wsMsgPong:
+		// Ответ на наш ping, ничего не делаем
+
+	case wsMsgSubscribe:
+		return c.subscribe(msg)
+
+	case wsMsgComplete:
+		// Клиент сам завершает операцию - complete в ответ не отправляем
+		c.mu.Lock()
+		if op, exists := c.operations[msg.ID]; exists {
+			delete(c.operations, msg.ID)
+			op.cancel()
+		}
+		c.mu.Unlock()
+
+	default:
+		c.close(wsCloseBadRequest, "Unknown message type")
+		return false
+	}
+
+	return true
+}
+
+// subscribe запускает операцию клиента в отдельной горутине
+func (c *wsConnection) subscribe(msg wsMessage) bool {
+	var payload wsSubscribePayload
+	if msg.ID == "" || json.Unmarshal(msg.Payload, &payload) != nil {
+		c.close(wsCloseBadRequest, "Invalid subscribe message")
+		return false
+	}
+
+	c.mu.Lock()
+	if !c.acknowledged {
+		c.mu.Unlock()
+		c.close(wsCloseUnauthorized, "Unauthorized")
+		return false
+	}
+	if _, exists := c.operations[msg.ID]; exists {
+		c.mu.Unlock()
+		c.close(wsCloseSubscriberExists, "Subscriber for "+msg.ID+" already exists")
+		return false
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+	op := &wsOperation{cancel: cancel}
+	c.operations[msg.ID] = op
+	c.mu.Unlock()
+
+	go c.execute(ctx, msg.ID, op, payload)
+	return true
+}
+
+// execute выполняет операцию и отправляет клиенту результаты
+func (c *wsConnection) execute(ctx context.Context, id string, op *wsOperation, payload wsSubscribePayload) {
+	defer op.cancel()
+
+	// Запрос по хэшу (APQ или список разрешенных операций)
+	query, err := c.persisted.Resolve(ctx, payload.Query, payload.Extensions.PersistedQuery)
+	if err != nil {
+		if c.finish(id, op) {
+			c.sendPayload(id, wsMsgError, errorResult(err).Errors)
+		}
+		return
+	}
+	params := graphql.Params{
+		Schema:         *c.schema,
+		RequestString:  query,
+		VariableValues: payload.Variables,
+		OperationName:  payload.OperationName,
+		Context:        ctx,
+	}
+
+	// Слишком сложный запрос отклоняется до выполнения
+	cost, rejected := checkQuery(params, c.limits)
+	if rejected != nil {
+		if c.finish(id, op) {
+			c.sendPayload(id, wsMsgError, rejected.Errors)
+		}
+		return
+	}
+
+	var results <-chan *graphql.Result
+	if isSubscription(query, payload.OperationName) {
+		results = graphql.Subscribe(params)
+	} else {
+		// Запросы и мутации тоже можно выполнять через WebSocket - один результат.
+		// Загрузчики подключаем только здесь: у подписки кэш жил бы между событиями
+		params.Context = WithLoaders(ctx)
+		single := make(chan *graphql.Result, 1)
+		single <- withCost(graphql.Do(params), cost)
+		close(single)
+		results = single
+	}
+
+	first := true
+	for result := range results {
+		// После отмены дочитываем канал, чтобы не блокировать горутину graphql
+		if ctx.Err() != nil {
+			continue
+		}
+
+		// Ошибка разбора или валидации до начала выполнения - сообщение error
+		if first && result.Data == nil && len(result.Errors) > 0 {
+			if c.finish(id, op) {
+				c.sendPayload(id, wsMsgError, result.Errors)
+			}
+			return
+		}
+		if first {
+			// Запрос прошел разбор и валидацию - его можно запомнить по хэшу
+			c.persisted.Register(ctx, payload.Query, payload.Extensions.PersistedQuery)
+		}
+		first = false
+
+		c.sendPayload(id, wsMsgNext, result)
+	}
+
+	if c.finish(id, op) {
+		c.send(wsMessage{ID: id, Type: wsMsgComplete})
+	}
+}
+
+// finish снимает операцию с учета. Возвращает false,
+// если клиент уже завершил ее сам и отвечать ему не нужно
+func (c *wsConnection) finish(id string, op *wsOperation) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.operations[id] != op {
+		return false
+	}
+	delete(c.operations, id)
+	return true
+}
+
+// cancelAll отменяет все операции при разрыве соединения
+func (c *wsConnection) cancelAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, op := range c.operations {
+		op.cancel()
+		delete(c.operations, id)
+	}
+}
+
+// sendPayload отправляет сообщение с JSON нагрузкой
+func (c *wsConnection) sendPayload(id, msgType string, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		data, _ = json.Marshal([]gqlerrors.FormattedError{gqlerrors.FormatError(err)})
+		msgType = wsMsgError
+	}
+	c.send(wsMessage{ID: id, Type: msgType, Payload: data})
+}
+
+// send отправляет сообщение клиенту
+func (c *wsConnection) send(msg wsMessage) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if err := c.conn.WriteJSON(msg); err != nil {
+		// Соединение сломано - чтение завершится с ошибкой и все отменит
+		c.conn.Close()
+	}
+}
+
+// close закрывает соединение с кодом из спецификации протокола
+func (c *wsConnection) close(code int, reason string) {
+	c.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, reason), time.Now().Add(wsWriteTimeout))
+	c.conn.Close()
+}
+
+// isSubscription проверяет, является ли выбранная операция подпиской
+func isSubscription(query, operationName string) bool {
+	document, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return false
+	}
+
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName != "" && (operation.Name == nil || operation.Name.Value != operationName) {
+			continue
+		}
+		return operation.Operation == ast.OperationTypeSubscription
+	}
+
+	return false
+}
@@ -0,0 +1,155 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"graphql-comments/internal/models"
+	"graphql-comments/internal/pubsub"
+	"graphql-comments/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+)
+
+func TestWebSocket_CommentAddedSubscription(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Контент"})
+	hub := pubsub.NewHub(pubsub.DefaultBufferSize)
+
+	schema, err := BuildSchema(Config{Storage: store, Hub: hub})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+
+	// 1. Поднимаем сервер и подключаемся по graphql-transport-ws
+	server := httptest.NewServer(NewHandler(schema, HandlerConfig{}))
+	defer server.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{wsProtocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Ошибка подключения: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	// 2. Инициализация соединения
+	conn.WriteJSON(wsMessage{Type: wsMsgConnectionInit})
+	var msg wsMessage
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != wsMsgConnectionAck {
+		t.Fatalf("Ожидали connection_ack, получили %+v (%v)", msg, err)
+	}
+
+	// 3. Подписываемся на новые комментарии поста
+	payload, _ := json.Marshal(wsSubscribePayload{
+		Query: `subscription { commentAdded(postId: "post_1") { id content } }`,
+	})
+	conn.WriteJSON(wsMessage{ID: "1", Type: wsMsgSubscribe, Payload: payload})
+
+	// Ждем, пока подписка зарегистрируется в хабе
+	for i := 0; hub.SubscriberCount("post_1") == 0; i++ {
+		if i > 100 {
+			t.Fatalf("Подписка не зарегистрировалась")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 4. Создаем комментарий через мутацию
+	result := graphql.Do(graphql.Params{
+		Schema:        *schema,
+		RequestString: `mutation { createComment(input: {postId: "post_1", content: "Привет"}) { id } }`,
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("Ошибки мутации: %v", result.Errors)
+	}
+
+	// 5. Клиент получает событие
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != wsMsgNext || msg.ID != "1" {
+		t.Fatalf("Ожидали next, получили %+v (%v)", msg, err)
+	}
+	if !strings.Contains(string(msg.Payload), "Привет") {
+		t.Errorf("Ожидали комментарий в событии, получили %s", msg.Payload)
+	}
+
+	// 6. После отключения клиента подписка удаляется из хаба
+	conn.Close()
+	for i := 0; hub.SubscriberCount("post_1") != 0; i++ {
+		if i > 100 {
+			t.Fatalf("Подписка не удалилась после отключения клиента")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWebSocket_SubscribeBeforeInit(t *testing.T) {
+	schema, err := BuildSchema(Config{Storage: storage.NewMemoryStorage()})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+
+	server := httptest.NewServer(NewHandler(schema, HandlerConfig{}))
+	defer server.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{wsProtocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Ошибка подключения: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	// Подписка без connection_init закрывает соединение с кодом 4401
+	payload, _ := json.Marshal(wsSubscribePayload{Query: `subscription { commentAdded(postId: "post_1") { id } }`})
+	conn.WriteJSON(wsMessage{ID: "1", Type: wsMsgSubscribe, Payload: payload})
+
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, wsCloseUnauthorized) {
+		t.Errorf("Ожидали закрытие с кодом 4401, получили %v", err)
+	}
+}
+
+func TestWebSocket_CheckOrigin(t *testing.T) {
+	schema, err := BuildSchema(Config{Storage: storage.NewMemoryStorage()})
+	if err != nil {
+		t.Fatalf("Ошибка создания схемы: %v", err)
+	}
+	server := httptest.NewServer(NewHandler(schema, HandlerConfig{AllowedOrigins: []string{"https://app.example.com"}}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		origin   string
+		accepted bool
+	}{
+		{"без Origin", "", true},
+		{"адрес сервера", server.URL, true},
+		{"разрешенный источник", "https://app.example.com", true},
+		{"чужой сайт", "https://evil.example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.origin != "" {
+				header.Set("Origin", tt.origin)
+			}
+			dialer := websocket.Dialer{Subprotocols: []string{wsProtocol}}
+			conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), header)
+			if conn != nil {
+				conn.Close()
+			}
+			if (err == nil) != tt.accepted {
+				t.Errorf("Ожидали accepted=%v, получили %v", tt.accepted, err)
+			}
+			if !tt.accepted && (resp == nil || resp.StatusCode != http.StatusForbidden) {
+				t.Errorf("Ожидали 403 для чужого источника, получили %v", resp)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package idgen
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// Kind - тип сущности, для которой генерируется ID
+type Kind string
+
+const (
+	KindPost    Kind = "post"
+	KindComment Kind = "comment"
+)
+
+// Generator - источник уникальных ID для постов и комментариев.
+// ctx - контекст запроса, генератор может обращаться к внешнему хранилищу
+type Generator interface {
+	NewID(ctx context.Context, kind Kind) (string, error)
+}
+
+// New создает генератор по имени из флага командной строки.
+// nodeID используется только генератором snowflake
+func New(name string, nodeID int64) (Generator, error) {
+	switch name {
+	case "uuidv7":
+		return NewUUIDv7(), nil
+	case "ulid":
+		return NewULID(), nil
+	case "snowflake":
+		return NewSnowflake(nodeID)
+	case "counter":
+		return NewCounter(), nil
+	default:
+		return nil, fmt.Errorf("неизвестный генератор ID: %s", name)
+	}
+}
+
+// Counter - генератор вида post_1, comment_1 на счетчиках в памяти.
+// Счетчики сбрасываются при перезапуске, поэтому подходит только для in-memory хранилища
+type Counter struct {
+	mu       sync.Mutex
+	counters map[Kind]int
+}
+
+// NewCounter создает новый Counter
+func NewCounter() *Counter {
+	return &Counter{counters: make(map[Kind]int)}
+}
+
+// NewID возвращает следующий ID для указанного типа сущности
+func (c *Counter) NewID(ctx context.Context, kind Kind) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counters[kind]++
+	return string(kind) + "_" + strconv.Itoa(c.counters[kind]), nil
+}
@@ -0,0 +1,94 @@
+package idgen
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCounter_NewID(t *testing.T) {
+	gen := NewCounter()
+
+	// Счетчики постов и комментариев независимы
+	post, _ := gen.NewID(context.Background(), KindPost)
+	comment, _ := gen.NewID(context.Background(), KindComment)
+	post2, _ := gen.NewID(context.Background(), KindPost)
+
+	if post != "post_1" || comment != "comment_1" || post2 != "post_2" {
+		t.Errorf("Неожиданные ID: %s, %s, %s", post, comment, post2)
+	}
+}
+
+func TestUUIDv7_Format(t *testing.T) {
+	gen := NewUUIDv7()
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id, err := gen.NewID(context.Background(), KindPost)
+		if err != nil {
+			t.Fatalf("Ошибка генерации: %v", err)
+		}
+		if !pattern.MatchString(id) {
+			t.Fatalf("ID не похож на UUIDv7: %s", id)
+		}
+		if seen[id] {
+			t.Fatalf("Повтор ID: %s", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestULID_MonotonicWithinMillisecond(t *testing.T) {
+	// Замораживаем время, чтобы все ID попали в одну миллисекунду
+	fixed := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	gen := NewULID()
+	gen.now = func() time.Time { return fixed }
+
+	prev := ""
+	for i := 0; i < 1000; i++ {
+		id, err := gen.NewID(context.Background(), KindComment)
+		if err != nil {
+			t.Fatalf("Ошибка генерации: %v", err)
+		}
+		if len(id) != 26 {
+			t.Fatalf("Ожидали 26 символов, получили %q", id)
+		}
+		if id <= prev {
+			t.Fatalf("ID не возрастают: %s после %s", id, prev)
+		}
+		prev = id
+	}
+}
+
+func TestSnowflake_UniqueAndIncreasing(t *testing.T) {
+	gen, err := NewSnowflake(7)
+	if err != nil {
+		t.Fatalf("Ошибка создания генератора: %v", err)
+	}
+
+	var prev int64
+	for i := 0; i < 10000; i++ {
+		id, err := gen.NewID(context.Background(), KindPost)
+		if err != nil {
+			t.Fatalf("Ошибка генерации: %v", err)
+		}
+		n, _ := strconv.ParseInt(id, 10, 64)
+		if n <= prev {
+			t.Fatalf("ID не возрастают: %d после %d", n, prev)
+		}
+		// Номер узла зашит в ID
+		if (n>>snowflakeSequenceBits)&snowflakeMaxNode != 7 {
+			t.Fatalf("Неверный номер узла в ID %d", n)
+		}
+		prev = n
+	}
+}
+
+func TestSnowflake_InvalidNode(t *testing.T) {
+	if _, err := NewSnowflake(snowflakeMaxNode + 1); err == nil {
+		t.Errorf("Ожидали ошибку для слишком большого номера узла")
+	}
+}
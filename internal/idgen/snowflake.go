@@ -0,0 +1,69 @@
+package idgen
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// snowflakeEpoch - начало отсчета времени для snowflake ID (2024-01-01 UTC)
+var snowflakeEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const (
+	snowflakeNodeBits     = 10
+	snowflakeSequenceBits = 12
+	snowflakeMaxNode      = 1<<snowflakeNodeBits - 1
+	snowflakeMaxSequence  = 1<<snowflakeSequenceBits - 1
+)
+
+// Snowflake - генератор 63-битных ID: 41 бит времени, 10 бит номера узла,
+// 12 бит счетчика внутри миллисекунды. Номер узла должен быть уникален
+// для каждой реплики сервера
+type Snowflake struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	nodeID   int64
+	lastMs   int64
+	sequence int64
+}
+
+// NewSnowflake создает новый Snowflake для узла nodeID (0-1023)
+func NewSnowflake(nodeID int64) (*Snowflake, error) {
+	if nodeID < 0 || nodeID > snowflakeMaxNode {
+		return nil, fmt.Errorf("номер узла snowflake должен быть от 0 до %d", snowflakeMaxNode)
+	}
+	return &Snowflake{now: time.Now, nodeID: nodeID}, nil
+}
+
+// NewID возвращает новый snowflake ID в десятичном виде
+func (g *Snowflake) NewID(ctx context.Context, kind Kind) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := g.now().Sub(snowflakeEpoch).Milliseconds()
+	if ms < g.lastMs {
+		return "", fmt.Errorf("часы ушли назад на %d мс, генерация ID невозможна", g.lastMs-ms)
+	}
+
+	if ms == g.lastMs {
+		g.sequence = (g.sequence + 1) & snowflakeMaxSequence
+		if g.sequence == 0 {
+			// Счетчик исчерпан - ждем следующую миллисекунду
+			for ms <= g.lastMs {
+				time.Sleep(100 * time.Microsecond)
+				ms = g.now().Sub(snowflakeEpoch).Milliseconds()
+			}
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.lastMs = ms
+
+	id := ms<<(snowflakeNodeBits+snowflakeSequenceBits) |
+		g.nodeID<<snowflakeSequenceBits |
+		g.sequence
+
+	return strconv.FormatInt(id, 10), nil
+}
@@ -0,0 +1,94 @@
+package idgen
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+// crockfordAlphabet - алфавит Crockford Base32, используемый в ULID
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID - генератор ULID: 48 бит времени и 80 случайных бит, 26 символов.
+// Внутри одной миллисекунды случайная часть увеличивается на единицу,
+// поэтому ID монотонно возрастают
+type ULID struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	lastMs  uint64
+	lastRnd [10]byte
+}
+
+// NewULID создает новый ULID
+func NewULID() *ULID {
+	return &ULID{now: time.Now}
+}
+
+// NewID возвращает новый ULID
+func (g *ULID) NewID(ctx context.Context, kind Kind) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(g.now().UnixMilli())
+	if ms <= g.lastMs {
+		// Та же миллисекунда (или часы ушли назад) - продолжаем последовательность
+		ms = g.lastMs
+		if !incrementBytes(g.lastRnd[:]) {
+			// Переполнение случайной части - переходим на следующую миллисекунду
+			ms++
+			if _, err := rand.Read(g.lastRnd[:]); err != nil {
+				return "", err
+			}
+		}
+	} else if _, err := rand.Read(g.lastRnd[:]); err != nil {
+		return "", err
+	}
+	g.lastMs = ms
+
+	var b [16]byte
+	b[0] = byte(ms >> 40)
+	b[1] = byte(ms >> 32)
+	b[2] = byte(ms >> 24)
+	b[3] = byte(ms >> 16)
+	b[4] = byte(ms >> 8)
+	b[5] = byte(ms)
+	copy(b[6:], g.lastRnd[:])
+
+	return encodeCrockford(b), nil
+}
+
+// incrementBytes увеличивает big-endian число на единицу.
+// Возвращает false при переполнении
+func incrementBytes(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeCrockford кодирует 128 бит в 26 символов Crockford Base32
+func encodeCrockford(b [16]byte) string {
+	var out [26]byte
+
+	// 128 бит не делятся на 5 нацело - первый символ несет старшие 3 бита
+	var acc uint64
+	bits := 0
+	pos := len(out) - 1
+	for i := len(b) - 1; i >= 0; i-- {
+		acc |= uint64(b[i]) << bits
+		bits += 8
+		for bits >= 5 {
+			out[pos] = crockfordAlphabet[acc&0x1f]
+			pos--
+			acc >>= 5
+			bits -= 5
+		}
+	}
+	out[pos] = crockfordAlphabet[acc&0x1f]
+
+	return string(out[:])
+}
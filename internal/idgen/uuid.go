@@ -0,0 +1,52 @@
+package idgen
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// UUIDv7 - генератор UUID версии 7 (RFC 9562):
+// 48 бит времени в миллисекундах и 74 случайных бита.
+// ID упорядочены по времени создания, что удобно для индексов
+type UUIDv7 struct {
+	now func() time.Time
+}
+
+// NewUUIDv7 создает новый UUIDv7
+func NewUUIDv7() *UUIDv7 {
+	return &UUIDv7{now: time.Now}
+}
+
+// NewID возвращает новый UUIDv7 в каноническом текстовом виде
+func (g *UUIDv7) NewID(ctx context.Context, kind Kind) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[6:]); err != nil {
+		return "", err
+	}
+
+	ms := uint64(g.now().UnixMilli())
+	b[0] = byte(ms >> 40)
+	b[1] = byte(ms >> 32)
+	b[2] = byte(ms >> 24)
+	b[3] = byte(ms >> 16)
+	b[4] = byte(ms >> 8)
+	b[5] = byte(ms)
+
+	b[6] = (b[6] & 0x0f) | 0x70 // версия 7
+	b[8] = (b[8] & 0x3f) | 0x80 // вариант RFC 9562
+
+	var out [36]byte
+	hex.Encode(out[0:8], b[0:4])
+	out[8] = '-'
+	hex.Encode(out[9:13], b[4:6])
+	out[13] = '-'
+	hex.Encode(out[14:18], b[6:8])
+	out[18] = '-'
+	hex.Encode(out[19:23], b[8:10])
+	out[23] = '-'
+	hex.Encode(out[24:36], b[10:16])
+
+	return string(out[:]), nil
+}
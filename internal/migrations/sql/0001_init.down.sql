@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS comment_revisions;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS posts;
+DROP SEQUENCE IF EXISTS comment_id_seq;
+DROP SEQUENCE IF EXISTS post_id_seq;
@@ -0,0 +1,73 @@
+-- Базовая схема. IF NOT EXISTS - чтобы базы, созданные старым schema.sql,
+-- можно было перевести на миграции без потери данных
+
+-- Последовательности для генерации ID силами PostgreSQL (-idgen=sequence)
+CREATE SEQUENCE IF NOT EXISTS post_id_seq;
+CREATE SEQUENCE IF NOT EXISTS comment_id_seq;
+
+CREATE TABLE IF NOT EXISTS posts (
+    id VARCHAR(50) PRIMARY KEY,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    allow_comments BOOLEAN NOT NULL DEFAULT TRUE,
+    author TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    version INT NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS comments (
+    id VARCHAR(50) PRIMARY KEY,
+    post_id VARCHAR(50) NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    parent_id VARCHAR(50) REFERENCES comments(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    author TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    version INT NOT NULL DEFAULT 1,
+    -- Время мягкого удаления: удаленный комментарий остается в дереве как заглушка
+    deleted_at TIMESTAMPTZ
+);
+
+-- Таблицы из старого schema.sql создавались без этих колонок и с created_at без часового пояса:
+-- CREATE TABLE IF NOT EXISTS их не трогает, поэтому достраиваем схему до текущей.
+-- Старые значения created_at считаются временем в часовом поясе сессии, как их и писал CURRENT_TIMESTAMP
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS allow_comments BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS author TEXT NOT NULL DEFAULT '';
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS author TEXT NOT NULL DEFAULT '';
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+UPDATE posts SET created_at = now() WHERE created_at IS NULL;
+ALTER TABLE posts ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN created_at SET DEFAULT now(),
+    ALTER COLUMN created_at SET NOT NULL;
+UPDATE comments SET created_at = now() WHERE created_at IS NULL;
+ALTER TABLE comments ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN created_at SET DEFAULT now(),
+    ALTER COLUMN created_at SET NOT NULL;
+
+-- Старые счетчики выдавали ID того же вида post_N и comment_N, что и последовательности:
+-- переводим последовательности за наибольший существующий номер, иначе первые вставки
+-- упрутся в первичный ключ
+SELECT setval('post_id_seq', COALESCE(MAX(substring(id FROM '^post_([0-9]{1,18})$')::BIGINT), 0) + 1, false)
+FROM posts;
+SELECT setval('comment_id_seq', COALESCE(MAX(substring(id FROM '^comment_([0-9]{1,18})$')::BIGINT), 0) + 1, false)
+FROM comments;
+
+-- История правок: предыдущие версии текста комментария
+CREATE TABLE IF NOT EXISTS comment_revisions (
+    comment_id VARCHAR(50) NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    content TEXT NOT NULL,
+    edited_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (comment_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_post_id ON comments(post_id);
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments(parent_id);
+CREATE INDEX IF NOT EXISTS idx_comments_deleted_at ON comments(deleted_at) WHERE deleted_at IS NOT NULL;
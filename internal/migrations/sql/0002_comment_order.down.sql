@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_comments_parent_reply_count;
+DROP INDEX IF EXISTS idx_comments_parent_score;
+DROP TRIGGER IF EXISTS comments_reply_count ON comments;
+DROP FUNCTION IF EXISTS comments_reply_count();
+ALTER TABLE comments DROP COLUMN IF EXISTS reply_count;
+ALTER TABLE comments DROP COLUMN IF EXISTS score;
@@ -0,0 +1,27 @@
+-- Поля для сортировки комментариев: рейтинг и число прямых ответов
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS score INT NOT NULL DEFAULT 0;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS reply_count INT NOT NULL DEFAULT 0;
+
+UPDATE comments c SET reply_count = r.cnt
+FROM (SELECT parent_id, COUNT(*) AS cnt FROM comments WHERE parent_id IS NOT NULL GROUP BY parent_id) r
+WHERE c.id = r.parent_id;
+
+-- reply_count поддерживается триггером, чтобы по нему работала keyset-пагинация
+CREATE OR REPLACE FUNCTION comments_reply_count() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' AND NEW.parent_id IS NOT NULL THEN
+        UPDATE comments SET reply_count = reply_count + 1 WHERE id = NEW.parent_id;
+    ELSIF TG_OP = 'DELETE' AND OLD.parent_id IS NOT NULL THEN
+        UPDATE comments SET reply_count = reply_count - 1 WHERE id = OLD.parent_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS comments_reply_count ON comments;
+CREATE TRIGGER comments_reply_count
+    AFTER INSERT OR DELETE ON comments
+    FOR EACH ROW EXECUTE FUNCTION comments_reply_count();
+
+CREATE INDEX IF NOT EXISTS idx_comments_parent_score ON comments(parent_id, score, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_comments_parent_reply_count ON comments(parent_id, reply_count, created_at, id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS comment_reactions;
+UPDATE comments SET score = 0;
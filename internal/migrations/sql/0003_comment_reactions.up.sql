@@ -0,0 +1,12 @@
+-- Голоса и эмодзи-реакции: одна запись на пользователя и комментарий.
+-- Записи без голоса и реакции удаляются, comments.score - сумма голосов
+CREATE TABLE IF NOT EXISTS comment_reactions (
+    comment_id VARCHAR(50) NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    value SMALLINT NOT NULL DEFAULT 0 CHECK (value BETWEEN -1 AND 1),
+    emoji TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (comment_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_comment_reactions_emoji ON comment_reactions(comment_id, emoji) WHERE emoji IS NOT NULL;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS post_bans;
+ALTER TABLE comments DROP COLUMN IF EXISTS locked_at;
+ALTER TABLE comments DROP COLUMN IF EXISTS hidden_at;
@@ -0,0 +1,24 @@
+DROP INDEX IF EXISTS idx_comments_pending;
+
+-- Возвращаем триггер из 0002: без статусов считаются все ответы
+CREATE OR REPLACE FUNCTION comments_reply_count() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' AND NEW.parent_id IS NOT NULL THEN
+        UPDATE comments SET reply_count = reply_count + 1 WHERE id = NEW.parent_id;
+    ELSIF TG_OP = 'DELETE' AND OLD.parent_id IS NOT NULL THEN
+        UPDATE comments SET reply_count = reply_count - 1 WHERE id = OLD.parent_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS comments_reply_count ON comments;
+CREATE TRIGGER comments_reply_count
+    AFTER INSERT OR DELETE ON comments
+    FOR EACH ROW EXECUTE FUNCTION comments_reply_count();
+
+-- Ответы, которые были на модерации, становятся обычными - пересчитываем счетчик
+UPDATE comments c SET reply_count = (SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id);
+
+ALTER TABLE comments DROP COLUMN IF EXISTS moderation_reason;
+ALTER TABLE comments DROP COLUMN IF EXISTS status;
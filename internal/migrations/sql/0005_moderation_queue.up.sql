@@ -0,0 +1,28 @@
+-- Премодерация: комментарии, отмеченные фильтрами, ждут проверки и не видны в дереве
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'PUBLISHED'
+    CHECK (status IN ('PUBLISHED', 'PENDING', 'REJECTED'));
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS moderation_reason TEXT NOT NULL DEFAULT '';
+
+-- reply_count считает только опубликованные ответы: одобренный ответ увеличивает его
+CREATE OR REPLACE FUNCTION comments_reply_count() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' AND NEW.parent_id IS NOT NULL AND NEW.status = 'PUBLISHED' THEN
+        UPDATE comments SET reply_count = reply_count + 1 WHERE id = NEW.parent_id;
+    ELSIF TG_OP = 'DELETE' AND OLD.parent_id IS NOT NULL AND OLD.status = 'PUBLISHED' THEN
+        UPDATE comments SET reply_count = reply_count - 1 WHERE id = OLD.parent_id;
+    ELSIF TG_OP = 'UPDATE' AND NEW.parent_id IS NOT NULL AND OLD.status <> NEW.status
+        AND 'PUBLISHED' IN (OLD.status, NEW.status) THEN
+        UPDATE comments SET reply_count = reply_count + CASE WHEN NEW.status = 'PUBLISHED' THEN 1 ELSE -1 END
+        WHERE id = NEW.parent_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS comments_reply_count ON comments;
+CREATE TRIGGER comments_reply_count
+    AFTER INSERT OR DELETE OR UPDATE OF status ON comments
+    FOR EACH ROW EXECUTE FUNCTION comments_reply_count();
+
+-- Очередь модерации читается по статусу в порядке создания
+CREATE INDEX IF NOT EXISTS idx_comments_pending ON comments(created_at, id) WHERE status = 'PENDING';
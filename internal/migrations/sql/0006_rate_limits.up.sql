@@ -0,0 +1,8 @@
+-- Состояние ограничителя запросов, общее для всех реплик:
+-- tat - теоретическое время прихода следующей операции (GCRA)
+CREATE TABLE IF NOT EXISTS rate_limits (
+    key TEXT PRIMARY KEY,
+    tat TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limits_tat ON rate_limits(tat);
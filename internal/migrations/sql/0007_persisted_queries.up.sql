@@ -0,0 +1,6 @@
+-- Тексты persisted запросов (APQ) по sha256, общие для всех реплик
+CREATE TABLE IF NOT EXISTS persisted_queries (
+    hash TEXT PRIMARY KEY,
+    query TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
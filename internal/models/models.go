@@ -1,11 +1,20 @@
 package models
 
+import "time"
+
 
 type Post struct {
 	ID       string     `json:"id"`       
 	Title    string     `json:"title"`    
 	Content  string     `json:"content"`  
 	Comments []*Comment `json:"comments"` 
+	// CommentsDisabled - комментарии к посту отключены. Нулевое значение
+	// разрешает комментарии, как и DEFAULT TRUE колонки allow_comments
+	CommentsDisabled bool `json:"commentsDisabled"`
+	Author    string    `json:"author"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Version   int       `json:"version"`
 }
 
 
@@ -15,6 +24,78 @@ type Comment struct {
 	ParentID *string    `json:"parentId"` 
 	Content  string     `json:"content"` 
 	Replies  []*Comment `json:"replies"`  
+	Author    string     `json:"author"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	Version   int        `json:"version"`
+	IsDeleted bool       `json:"isDeleted"`
+	DeletedAt *time.Time `json:"deletedAt"`
+	// Score - рейтинг, ReplyCount - число прямых ответов (для сортировки)
+	Score      int        `json:"score"`
+	ReplyCount int        `json:"replyCount"`
+	// Скрыт модератором / ветка закрыта для новых ответов
+	IsHidden   bool       `json:"isHidden"`
+	IsLocked   bool       `json:"isLocked"`
+	// Status - состояние премодерации (пустое при создании - PUBLISHED),
+	// ModerationReason - почему фильтры отправили комментарий на проверку
+	Status           CommentStatus `json:"status"`
+	ModerationReason string        `json:"moderationReason"`
+}
+
+
+// CommentStatus - состояние комментария в очереди модерации.
+// Комментарии, кроме опубликованных, видны только модераторам и автору
+type CommentStatus string
+
+const (
+	CommentPublished CommentStatus = "PUBLISHED"
+	CommentPending   CommentStatus = "PENDING"
+	CommentRejected  CommentStatus = "REJECTED"
+)
+
+
+type CommentRevision struct {
+	CommentID string    `json:"commentId"`
+	Version   int       `json:"version"`
+	Content   string    `json:"content"`
+	EditedAt  time.Time `json:"editedAt"`
+}
+
+
+// Reaction - голос (-1, 0 или 1) и эмодзи-реакция пользователя на комментарий
+type Reaction struct {
+	CommentID string    `json:"commentId"`
+	UserID    string    `json:"userId"`
+	Value     int       `json:"value"`
+	Emoji     *string   `json:"emoji"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+
+type ReactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+}
+
+
+// PostBan - запрет пользователю комментировать пост
+type PostBan struct {
+	PostID    string    `json:"postId"`
+	UserID    string    `json:"userId"`
+	Reason    string    `json:"reason"`
+	BannedBy  string    `json:"bannedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+
+// AuditEntry - запись журнала действий модераторов
+type AuditEntry struct {
+	ID        int64     `json:"id"`
+	ActorID   string    `json:"actorId"`
+	Action    string    `json:"action"`
+	TargetID  string    `json:"targetId"`
+	Details   string    `json:"details"`
+	CreatedAt time.Time `json:"createdAt"`
 }
 
 
@@ -29,3 +110,62 @@ type CreateCommentInput struct {
 	ParentID *string `json:"parentId"` 
 	Content  string  `json:"content"`  
 }
+
+
+type UpdatePostInput struct {
+	ID      string  `json:"id"`
+	Title   *string `json:"title"`
+	Content *string `json:"content"`
+	Version int     `json:"version"`
+}
+
+
+type UpdateCommentInput struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+	Version int    `json:"version"`
+	// ModerationReason - причина, по которой фильтры отметили правку: непустая
+	// отклоняет правку опубликованного комментария, а неопубликованный отправляет
+	// на проверку (PENDING); пустая статус не меняет
+	ModerationReason string `json:"-"`
+}
+
+
+type CommentThread struct {
+	Comment   *Comment   `json:"comment"`
+	Ancestors []*Comment `json:"ancestors"`
+}
+
+
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor"`
+	EndCursor       *string `json:"endCursor"`
+}
+
+
+type PostEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *Post  `json:"node"`
+}
+
+
+type PostConnection struct {
+	Edges      []*PostEdge `json:"edges"`
+	PageInfo   *PageInfo   `json:"pageInfo"`
+	TotalCount int         `json:"totalCount"`
+}
+
+
+type CommentEdge struct {
+	Cursor string   `json:"cursor"`
+	Node   *Comment `json:"node"`
+}
+
+
+type CommentConnection struct {
+	Edges      []*CommentEdge `json:"edges"`
+	PageInfo   *PageInfo      `json:"pageInfo"`
+	TotalCount int            `json:"totalCount"`
+}
@@ -0,0 +1,167 @@
+package moderation
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"graphql-comments/internal/models"
+)
+
+// BannedWords отмечает комментарии со словами из списка (без учета регистра)
+type BannedWords struct {
+	words map[string]bool
+}
+
+// NewBannedWords создает фильтр по списку запрещенных слов
+func NewBannedWords(words []string) *BannedWords {
+	f := &BannedWords{words: make(map[string]bool, len(words))}
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			f.words[word] = true
+		}
+	}
+	return f
+}
+
+// LoadBannedWords читает список слов из файла: по слову в строке, # - комментарий
+func LoadBannedWords(path string) (*BannedWords, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewBannedWords(words), nil
+}
+
+func (f *BannedWords) Name() string { return "bannedWords" }
+
+// Check ищет запрещенные слова среди слов комментария
+func (f *BannedWords) Check(ctx context.Context, comment *models.Comment) (Verdict, error) {
+	words := strings.FieldsFunc(strings.ToLower(comment.Content), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if f.words[word] {
+			return Verdict{Flagged: true, Reason: fmt.Sprintf("запрещенное слово %q", word)}, nil
+		}
+	}
+	return Verdict{}, nil
+}
+
+// linkPattern - ссылки с протоколом и без него (www.)
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// LinkLimit отмечает комментарии, в которых больше Max ссылок
+type LinkLimit struct {
+	Max int
+}
+
+func (f LinkLimit) Name() string { return "linkLimit" }
+
+// Check считает ссылки в тексте комментария
+func (f LinkLimit) Check(ctx context.Context, comment *models.Comment) (Verdict, error) {
+	if links := len(linkPattern.FindAllString(comment.Content, -1)); links > f.Max {
+		return Verdict{Flagged: true, Reason: fmt.Sprintf("ссылок: %d, допустимо %d", links, f.Max)}, nil
+	}
+	return Verdict{}, nil
+}
+
+// AllCaps отмечает комментарии, написанные в основном заглавными буквами
+type AllCaps struct {
+	// MinLetters - короткие тексты ("ОК", "СПАСИБО") не проверяются
+	MinLetters int
+	// Ratio - доля заглавных среди букв, начиная с которой текст считается криком
+	Ratio float64
+}
+
+// DefaultAllCaps возвращает фильтр с порогами по умолчанию
+func DefaultAllCaps() AllCaps {
+	return AllCaps{MinLetters: 12, Ratio: 0.8}
+}
+
+func (f AllCaps) Name() string { return "allCaps" }
+
+// Check считает долю заглавных букв
+func (f AllCaps) Check(ctx context.Context, comment *models.Comment) (Verdict, error) {
+	var letters, upper int
+	for _, r := range comment.Content {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+
+	if letters >= f.MinLetters && float64(upper) >= f.Ratio*float64(letters) {
+		return Verdict{Flagged: true, Reason: "текст заглавными буквами"}, nil
+	}
+	return Verdict{}, nil
+}
+
+// CommentSource - откуда фильтр дубликатов берет уже опубликованные комментарии.
+// Ему удовлетворяет storage.Storage
+type CommentSource interface {
+	GetCommentsByPostID(ctx context.Context, postID string) ([]*models.Comment, error)
+}
+
+// Duplicate отмечает комментарии, повторяющие уже опубликованный в том же посте текст
+// (без учета регистра и пробелов)
+type Duplicate struct {
+	Comments CommentSource
+	// MinLength - короткие ответы ("+1", "спасибо") повторяются естественно и не проверяются
+	MinLength int
+}
+
+func (f Duplicate) Name() string { return "duplicate" }
+
+// Check сравнивает текст с комментариями поста. Правка не сравнивается с самим комментарием
+func (f Duplicate) Check(ctx context.Context, comment *models.Comment) (Verdict, error) {
+	content := normalizeContent(comment.Content)
+	if len([]rune(content)) < f.MinLength {
+		return Verdict{}, nil
+	}
+
+	existing, err := f.Comments.GetCommentsByPostID(ctx, comment.PostID)
+	if err != nil {
+		return Verdict{}, err
+	}
+	for _, other := range existing {
+		if other.ID != comment.ID && !other.IsDeleted && normalizeContent(other.Content) == content {
+			return Verdict{Flagged: true, Reason: "повторяет комментарий " + other.ID}, nil
+		}
+	}
+	return Verdict{}, nil
+}
+
+// normalizeContent приводит текст к нижнему регистру и схлопывает пробелы
+func normalizeContent(content string) string {
+	return strings.Join(strings.Fields(strings.ToLower(content)), " ")
+}
+
+var (
+	_ ContentFilter = (*BannedWords)(nil)
+	_ ContentFilter = LinkLimit{}
+	_ ContentFilter = AllCaps{}
+	_ ContentFilter = Duplicate{}
+)
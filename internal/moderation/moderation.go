@@ -0,0 +1,56 @@
+package moderation
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"graphql-comments/internal/models"
+)
+
+// Verdict - решение фильтра о комментарии
+type Verdict struct {
+	// Flagged - комментарий нужно отправить на проверку модератору
+	Flagged bool
+	// Reason - почему фильтр отметил комментарий (видно модератору в очереди)
+	Reason string
+}
+
+// ContentFilter проверяет новый комментарий до сохранения.
+// Ошибка фильтра не отклоняет комментарий, а отправляет его на модерацию
+type ContentFilter interface {
+	// Name - короткое имя фильтра для причины в очереди и логов
+	Name() string
+	Check(ctx context.Context, comment *models.Comment) (Verdict, error)
+}
+
+// Pipeline прогоняет комментарий через все фильтры по порядку
+type Pipeline struct {
+	filters []ContentFilter
+}
+
+// NewPipeline создает цепочку из встроенных и собственных фильтров
+func NewPipeline(filters ...ContentFilter) *Pipeline {
+	return &Pipeline{filters: filters}
+}
+
+// Check возвращает общее решение: комментарий отмечен, если его отметил хотя бы один фильтр.
+// Причины всех сработавших фильтров собираются в одну строку "имя: причина; ..."
+func (p *Pipeline) Check(ctx context.Context, comment *models.Comment) Verdict {
+	var reasons []string
+	for _, filter := range p.filters {
+		verdict, err := filter.Check(ctx, comment)
+		if err != nil {
+			log.Printf("Фильтр %s не проверил комментарий %s, отправляем на модерацию: %v", filter.Name(), comment.ID, err)
+			verdict = Verdict{Flagged: true, Reason: "ошибка проверки"}
+		}
+		if verdict.Flagged {
+			reasons = append(reasons, filter.Name()+": "+verdict.Reason)
+		}
+	}
+
+	if len(reasons) == 0 {
+		return Verdict{}
+	}
+	return Verdict{Flagged: true, Reason: strings.Join(reasons, "; ")}
+}
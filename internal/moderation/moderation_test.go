@@ -0,0 +1,83 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"graphql-comments/internal/models"
+)
+
+// staticSource - фиксированный набор опубликованных комментариев для фильтра дубликатов
+type staticSource []*models.Comment
+
+func (s staticSource) GetCommentsByPostID(ctx context.Context, postID string) ([]*models.Comment, error) {
+	return s, nil
+}
+
+// failingFilter всегда возвращает ошибку
+type failingFilter struct{}
+
+func (failingFilter) Name() string { return "failing" }
+
+func (failingFilter) Check(ctx context.Context, comment *models.Comment) (Verdict, error) {
+	return Verdict{}, errors.New("сервис недоступен")
+}
+
+func TestFilters(t *testing.T) {
+	existing := staticSource{
+		{ID: "comment_1", PostID: "post_1", Content: "Отличная статья, спасибо автору!"},
+		{ID: "comment_2", PostID: "post_1", Content: "+1"},
+	}
+
+	tests := []struct {
+		name    string
+		filter  ContentFilter
+		content string
+		flagged bool
+	}{
+		{"запрещенное слово", NewBannedWords([]string{"Казино"}), "Лучшее казино!", true},
+		{"слово внутри другого", NewBannedWords([]string{"казино"}), "Казиноподобные игры", false},
+		{"ссылок в пределах лимита", LinkLimit{Max: 1}, "Смотрите https://example.com", false},
+		{"слишком много ссылок", LinkLimit{Max: 1}, "http://a.ru и www.b.ru", true},
+		{"крик", DefaultAllCaps(), "ЭТО ВОЗМУТИТЕЛЬНО, ВЕРНИТЕ ДЕНЬГИ", true},
+		{"короткий крик", DefaultAllCaps(), "ОК", false},
+		{"обычный текст", DefaultAllCaps(), "Согласен с предыдущим комментарием", false},
+		{"дубликат", Duplicate{Comments: existing, MinLength: 10}, "  отличная статья,   СПАСИБО автору!", true},
+		{"короткий повтор", Duplicate{Comments: existing, MinLength: 10}, "+1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comment := &models.Comment{ID: "new", PostID: "post_1", Content: tt.content}
+			verdict, err := tt.filter.Check(context.Background(), comment)
+			if err != nil {
+				t.Fatalf("Ошибка фильтра: %v", err)
+			}
+			if verdict.Flagged != tt.flagged {
+				t.Errorf("Ожидали flagged=%v, получили %+v", tt.flagged, verdict)
+			}
+		})
+	}
+}
+
+func TestPipeline_Check(t *testing.T) {
+	pipeline := NewPipeline(NewBannedWords([]string{"спам"}), LinkLimit{Max: 0}, failingFilter{})
+	comment := &models.Comment{ID: "new", PostID: "post_1", Content: "спам https://example.com"}
+
+	// Причины всех сработавших фильтров, ошибка фильтра тоже отправляет на модерацию
+	verdict := pipeline.Check(context.Background(), comment)
+	if !verdict.Flagged {
+		t.Fatal("Ожидали отмеченный комментарий")
+	}
+	for _, name := range []string{"bannedWords:", "linkLimit:", "failing:"} {
+		if !strings.Contains(verdict.Reason, name) {
+			t.Errorf("Ожидали %s в причине %q", name, verdict.Reason)
+		}
+	}
+
+	if verdict := NewPipeline(LinkLimit{Max: 1}).Check(context.Background(), comment); verdict.Flagged {
+		t.Errorf("Ожидали чистый комментарий, получили %+v", verdict)
+	}
+}
@@ -0,0 +1,182 @@
+package persisted
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Коды ошибок в extensions.code. Совпадают с кодами Apollo: клиент с APQ,
+// получив PERSISTED_QUERY_NOT_FOUND, повторяет запрос вместе с текстом
+const (
+	CodeNotFound     = "PERSISTED_QUERY_NOT_FOUND"
+	CodeNotSupported = "PERSISTED_QUERY_NOT_SUPPORTED"
+	CodeHashMismatch = "PERSISTED_QUERY_HASH_MISMATCH"
+	CodeNotInList    = "PERSISTED_QUERY_NOT_IN_LIST"
+)
+
+// Extension - extensions.persistedQuery запроса
+type Extension struct {
+	Version    int    `json:"version"`
+	SHA256Hash string `json:"sha256Hash"`
+}
+
+// Error - ошибка разрешения persisted запроса. Реализует gqlerrors.ExtendedError
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Extensions возвращает код ошибки
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.Code}
+}
+
+// Hash возвращает sha256 текста запроса в hex, как его считают клиенты
+func Hash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// Store хранит тексты запросов по хэшу
+type Store interface {
+	// Get возвращает текст запроса (false - хэш не зарегистрирован)
+	Get(ctx context.Context, hash string) (string, bool, error)
+	// Put регистрирует текст запроса
+	Put(ctx context.Context, hash, query string) error
+}
+
+// Queries разрешает хэши запросов в тексты. Режим APQ регистрирует новые запросы
+// в Store по первому запросу с текстом, режим списка выполняет только операции из манифеста.
+// nil Queries - persisted запросы выключены
+type Queries struct {
+	store     Store
+	manifest  map[string]string
+	allowlist bool
+}
+
+// NewAPQ создает разрешение запросов в режиме APQ. Операции манифеста (может быть nil)
+// доступны по хэшу без регистрации
+func NewAPQ(store Store, manifest map[string]string) *Queries {
+	return &Queries{store: store, manifest: manifest}
+}
+
+// NewAllowlist создает строгий режим: выполняются только операции манифеста,
+// по хэшу или по тексту, хэш которого есть в манифесте
+func NewAllowlist(manifest map[string]string) *Queries {
+	return &Queries{manifest: manifest, allowlist: true}
+}
+
+// Resolve возвращает текст запроса для выполнения. query - текст из запроса клиента (может быть пустым),
+// ext - extensions.persistedQuery (nil, если клиент его не прислал)
+func (q *Queries) Resolve(ctx context.Context, query string, ext *Extension) (string, error) {
+	if q == nil {
+		if ext != nil {
+			return "", &Error{Code: CodeNotSupported, Message: "persisted запросы не поддерживаются"}
+		}
+		return query, nil
+	}
+	if ext == nil {
+		if q.allowlist {
+			if _, ok := q.manifest[Hash(query)]; !ok {
+				return "", &Error{Code: CodeNotInList, Message: "запрос отсутствует в списке разрешенных"}
+			}
+		}
+		return query, nil
+	}
+
+	if ext.Version != 1 {
+		return "", &Error{Code: CodeNotSupported, Message: fmt.Sprintf("версия persisted запроса %d не поддерживается", ext.Version)}
+	}
+	hash := strings.ToLower(ext.SHA256Hash)
+
+	if query != "" {
+		// Текст с хэшем - регистрация: хэш должен совпадать с текстом
+		if Hash(query) != hash {
+			return "", &Error{Code: CodeHashMismatch, Message: "sha256Hash не совпадает с текстом запроса"}
+		}
+		if q.allowlist {
+			if _, ok := q.manifest[hash]; !ok {
+				return "", &Error{Code: CodeNotInList, Message: "запрос отсутствует в списке разрешенных"}
+			}
+		}
+		return query, nil
+	}
+
+	if stored, ok := q.manifest[hash]; ok {
+		return stored, nil
+	}
+	if q.allowlist {
+		return "", &Error{Code: CodeNotInList, Message: "запрос отсутствует в списке разрешенных"}
+	}
+	stored, ok, err := q.store.Get(ctx, hash)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		// Сообщение как у Apollo: старые клиенты проверяют текст, а не код
+		return "", &Error{Code: CodeNotFound, Message: "PersistedQueryNotFound"}
+	}
+	return stored, nil
+}
+
+// Register сохраняет текст запроса, присланный вместе с хэшем. Вызывается после
+// успешного разбора и валидации, чтобы в хранилище не попадали произвольные строки.
+// Ошибка хранилища только логируется: запрос уже выполнен
+func (q *Queries) Register(ctx context.Context, query string, ext *Extension) {
+	if q == nil || q.allowlist || ext == nil || query == "" {
+		return
+	}
+	hash := Hash(query)
+	if _, ok := q.manifest[hash]; ok {
+		return
+	}
+	if err := q.store.Put(ctx, hash, query); err != nil {
+		log.Printf("Ошибка регистрации persisted запроса %s: %v", hash, err)
+	}
+}
+
+// manifestFile - манифест в формате Apollo (apollo-persisted-query-manifest)
+type manifestFile struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	Operations []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+		Body string `json:"body"`
+	} `json:"operations"`
+}
+
+// LoadManifest читает манифест операций в формате Apollo и возвращает тексты по хэшу.
+// id каждой операции должен быть sha256 ее текста
+func LoadManifest(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file manifestFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if file.Format != "apollo-persisted-query-manifest" || file.Version != 1 {
+		return nil, fmt.Errorf("%s: ожидали format apollo-persisted-query-manifest версии 1", path)
+	}
+
+	manifest := make(map[string]string, len(file.Operations))
+	for _, operation := range file.Operations {
+		if Hash(operation.Body) != strings.ToLower(operation.ID) {
+			return nil, fmt.Errorf("%s: операция %s: id не совпадает с sha256 текста", path, operation.Name)
+		}
+		manifest[strings.ToLower(operation.ID)] = operation.Body
+	}
+	return manifest, nil
+}
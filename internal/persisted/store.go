@@ -0,0 +1,164 @@
+package persisted
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+)
+
+// LRUStore хранит последние capacity запросов в памяти. С next (например, PostgresStore)
+// работает как кэш перед ним: промахи читаются из next, регистрация пишется в оба
+type LRUStore struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // начало - последний использованный
+	entries  map[string]*list.Element
+	next     Store
+}
+
+// lruEntry - элемент списка LRUStore
+type lruEntry struct {
+	hash  string
+	query string
+}
+
+// NewLRUStore создает хранилище на capacity запросов; next может быть nil
+func NewLRUStore(capacity int, next Store) *LRUStore {
+	return &LRUStore{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		next:     next,
+	}
+}
+
+// Get реализует Store
+func (s *LRUStore) Get(ctx context.Context, hash string) (string, bool, error) {
+	s.mu.Lock()
+	if element, ok := s.entries[hash]; ok {
+		s.order.MoveToFront(element)
+		query := element.Value.(*lruEntry).query
+		s.mu.Unlock()
+		return query, true, nil
+	}
+	s.mu.Unlock()
+
+	if s.next == nil {
+		return "", false, nil
+	}
+	query, ok, err := s.next.Get(ctx, hash)
+	if err != nil || !ok {
+		return "", false, err
+	}
+	s.add(hash, query)
+	return query, true, nil
+}
+
+// Put реализует Store
+func (s *LRUStore) Put(ctx context.Context, hash, query string) error {
+	if s.next != nil {
+		if err := s.next.Put(ctx, hash, query); err != nil {
+			return err
+		}
+	}
+	s.add(hash, query)
+	return nil
+}
+
+// add кладет запрос в начало списка и вытесняет самые давние сверх capacity
+func (s *LRUStore) add(hash, query string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.entries[hash]; ok {
+		s.order.MoveToFront(element)
+		return
+	}
+	s.entries[hash] = s.order.PushFront(&lruEntry{hash: hash, query: query})
+	for s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*lruEntry).hash)
+	}
+}
+
+// Len возвращает число запросов в памяти
+func (s *LRUStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+// PostgresStore хранит запросы в таблице persisted_queries (миграция 0007),
+// поэтому зарегистрированный на одной реплике запрос доступен на всех.
+// Зарегистрировать запрос может любой клиент, поэтому таблица ограничена
+// maxEntries записями, а старые записи удаляет RunCleanup
+type PostgresStore struct {
+	db         *sql.DB
+	maxEntries int
+}
+
+// NewPostgresStore создает хранилище запросов в PostgreSQL не больше чем на maxEntries запросов
+func NewPostgresStore(db *sql.DB, maxEntries int) *PostgresStore {
+	return &PostgresStore{db: db, maxEntries: maxEntries}
+}
+
+// Get реализует Store
+func (s *PostgresStore) Get(ctx context.Context, hash string) (string, bool, error) {
+	var query string
+	err := s.db.QueryRowContext(ctx, `SELECT query FROM persisted_queries WHERE hash = $1`, hash).Scan(&query)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return query, true, nil
+}
+
+// Put реализует Store. Текст по хэшу не меняется, поэтому повторная регистрация ничего не делает.
+// В заполненную таблицу запрос не добавляется: клиент APQ продолжит присылать текст целиком
+func (s *PostgresStore) Put(ctx context.Context, hash, query string) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO persisted_queries (hash, query)
+		SELECT $1, $2 WHERE (SELECT count(*) FROM persisted_queries) < $3
+		ON CONFLICT (hash) DO NOTHING`,
+		hash, query, s.maxEntries)
+	return err
+}
+
+// DeleteOlderThan удаляет запросы, зарегистрированные раньше before
+func (s *PostgresStore) DeleteOlderThan(ctx context.Context, before time.Time) (int, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM persisted_queries WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	return int(deleted), err
+}
+
+// RunCleanup раз в interval удаляет запросы старше ttl, пока не отменен ctx.
+// Удаленный запрос клиент APQ просто зарегистрирует заново
+func (s *PostgresStore) RunCleanup(ctx context.Context, ttl, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.DeleteOlderThan(ctx, time.Now().Add(-ttl)); err != nil {
+				log.Printf("Ошибка очистки persisted запросов: %v", err)
+			}
+		}
+	}
+}
+
+var (
+	_ Store = (*LRUStore)(nil)
+	_ Store = (*PostgresStore)(nil)
+)
@@ -0,0 +1,109 @@
+package policy
+
+import (
+	"errors"
+
+	"graphql-comments/internal/auth"
+)
+
+// Ошибки авторизации. Слой API сопоставляет им коды UNAUTHENTICATED и FORBIDDEN
+var (
+	ErrUnauthenticated = errors.New("требуется аутентификация")
+	ErrForbidden       = errors.New("недостаточно прав для операции")
+)
+
+// Action - операция, на которую проверяются права
+type Action string
+
+const (
+	CreatePost       Action = "createPost"
+	UpdatePost       Action = "updatePost"
+	DeletePost       Action = "deletePost"
+	SetAllowComments Action = "setAllowComments"
+	CreateComment    Action = "createComment"
+	UpdateComment    Action = "updateComment"
+	DeleteComment    Action = "deleteComment"
+	RestoreComment   Action = "restoreComment"
+	VoteComment      Action = "voteComment"
+	React            Action = "react"
+	HideComment      Action = "hideComment"
+	LockThread       Action = "lockThread"
+	BanUserFromPost  Action = "banUserFromPost"
+	ReadAuditLog     Action = "readAuditLog"
+	ModerateComments Action = "moderateComments"
+)
+
+// Resource - запись, над которой выполняется операция
+type Resource struct {
+	// OwnerID - автор записи (пусто для операций создания)
+	OwnerID string
+}
+
+// Policy решает, может ли пользователь выполнить операцию.
+// viewer == nil - анонимный запрос
+type Policy interface {
+	Authorize(viewer *auth.Viewer, action Action, resource Resource) error
+}
+
+// rule - кто может выполнять операцию
+type rule struct {
+	// minRole - минимальная роль для любой записи
+	minRole auth.Role
+	// owner - автору записи роль не нужна
+	owner bool
+	// anonymous - операция доступна анонимно, если аутентификация не обязательна
+	anonymous bool
+}
+
+// rules - таблица прав. Модераторы удаляют и скрывают чужие комментарии,
+// но не правят их текст; чужие посты удаляет только администратор
+var rules = map[Action]rule{
+	CreatePost:       {minRole: auth.RoleUser, anonymous: true},
+	UpdatePost:       {owner: true, anonymous: true},
+	DeletePost:       {minRole: auth.RoleAdmin, owner: true, anonymous: true},
+	SetAllowComments: {minRole: auth.RoleModerator, owner: true, anonymous: true},
+	CreateComment:    {minRole: auth.RoleUser, anonymous: true},
+	UpdateComment:    {owner: true, anonymous: true},
+	DeleteComment:    {minRole: auth.RoleModerator, owner: true, anonymous: true},
+	RestoreComment:   {minRole: auth.RoleModerator, owner: true, anonymous: true},
+	VoteComment:      {minRole: auth.RoleUser, anonymous: true},
+	React:            {minRole: auth.RoleUser, anonymous: true},
+	HideComment:      {minRole: auth.RoleModerator},
+	LockThread:       {minRole: auth.RoleModerator},
+	BanUserFromPost:  {minRole: auth.RoleModerator},
+	ReadAuditLog:     {minRole: auth.RoleAdmin},
+	ModerateComments: {minRole: auth.RoleModerator},
+}
+
+// RoleBased - политика на основе ролей и авторства
+type RoleBased struct {
+	// RequireAuth - анонимные запросы не могут ничего менять.
+	// Без него анонимам доступны обычные операции без проверки авторства,
+	// как до появления аутентификации
+	RequireAuth bool
+}
+
+// Authorize реализует Policy
+func (p RoleBased) Authorize(viewer *auth.Viewer, action Action, resource Resource) error {
+	rule, known := rules[action]
+	if !known {
+		return ErrForbidden
+	}
+
+	if viewer == nil {
+		if rule.anonymous && !p.RequireAuth {
+			return nil
+		}
+		return ErrUnauthenticated
+	}
+
+	if rule.owner && resource.OwnerID != "" && resource.OwnerID == viewer.ID {
+		return nil
+	}
+	if rule.minRole != "" && viewer.HasRole(rule.minRole) {
+		return nil
+	}
+	return ErrForbidden
+}
+
+var _ Policy = RoleBased{}
@@ -0,0 +1,52 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+
+	"graphql-comments/internal/auth"
+)
+
+func TestRoleBased_Authorize(t *testing.T) {
+	user := &auth.Viewer{ID: "alice", Role: auth.RoleUser}
+	moderator := &auth.Viewer{ID: "moder", Role: auth.RoleModerator}
+	admin := &auth.Viewer{ID: "root", Role: auth.RoleAdmin}
+	bobs := Resource{OwnerID: "bob"}
+	alices := Resource{OwnerID: "alice"}
+
+	tests := []struct {
+		name        string
+		requireAuth bool
+		viewer      *auth.Viewer
+		action      Action
+		resource    Resource
+		want        error
+	}{
+		{"аноним создает пост без обязательной аутентификации", false, nil, CreatePost, Resource{}, nil},
+		{"аноним создает пост с обязательной аутентификацией", true, nil, CreatePost, Resource{}, ErrUnauthenticated},
+		{"аноним не модерирует", false, nil, HideComment, Resource{}, ErrUnauthenticated},
+		{"пользователь правит свой комментарий", true, user, UpdateComment, alices, nil},
+		{"пользователь не правит чужой комментарий", true, user, UpdateComment, bobs, ErrForbidden},
+		{"модератор не правит чужой текст", true, moderator, UpdateComment, bobs, ErrForbidden},
+		{"модератор удаляет чужой комментарий", true, moderator, DeleteComment, bobs, nil},
+		{"модератор не удаляет чужой пост", true, moderator, DeletePost, bobs, ErrForbidden},
+		{"администратор удаляет чужой пост", true, admin, DeletePost, bobs, nil},
+		{"пользователь не скрывает комментарии", true, user, HideComment, Resource{}, ErrForbidden},
+		{"модератор банит", true, moderator, BanUserFromPost, Resource{}, nil},
+		{"модератор не читает журнал", true, moderator, ReadAuditLog, Resource{}, ErrForbidden},
+		{"администратор читает журнал", true, admin, ReadAuditLog, Resource{}, nil},
+		{"модератор разбирает очередь", true, moderator, ModerateComments, Resource{}, nil},
+		{"пользователь не разбирает очередь", true, user, ModerateComments, Resource{}, ErrForbidden},
+		{"неизвестная роль без прав", true, &auth.Viewer{ID: "x", Role: "root"}, CreateComment, Resource{}, ErrForbidden},
+		{"неизвестная операция запрещена", false, admin, Action("dropDatabase"), Resource{}, ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RoleBased{RequireAuth: tt.requireAuth}.Authorize(tt.viewer, tt.action, tt.resource)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Ожидали %v, получили %v", tt.want, err)
+			}
+		})
+	}
+}
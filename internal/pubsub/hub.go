@@ -0,0 +1,111 @@
+package pubsub
+
+import (
+	"sync"
+
+	"graphql-comments/internal/models"
+)
+
+// DefaultBufferSize - размер буфера подписчика по умолчанию
+const DefaultBufferSize = 16
+
+// Hub - in-process шина событий о новых комментариях.
+// Подписчики группируются по ID поста, на один пост может быть подписано сколько угодно клиентов.
+type Hub struct {
+	mu         sync.RWMutex
+	subs       map[string]map[*Subscription]struct{}
+	bufferSize int
+}
+
+// Subscription - подписка на новые комментарии одного поста
+type Subscription struct {
+	hub    *Hub
+	postID string
+	ch     chan *models.Comment
+	closed bool // защищено hub.mu
+}
+
+// NewHub создает новый Hub. bufferSize - сколько событий может накопиться
+// у подписчика, прежде чем он будет отключен как медленный
+func NewHub(bufferSize int) *Hub {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+	return &Hub{
+		subs:       make(map[string]map[*Subscription]struct{}),
+		bufferSize: bufferSize,
+	}
+}
+
+// Subscribe подписывает на новые комментарии поста
+func (h *Hub) Subscribe(postID string) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := &Subscription{
+		hub:    h,
+		postID: postID,
+		ch:     make(chan *models.Comment, h.bufferSize),
+	}
+
+	if h.subs[postID] == nil {
+		h.subs[postID] = make(map[*Subscription]struct{})
+	}
+	h.subs[postID][sub] = struct{}{}
+
+	return sub
+}
+
+// Publish рассылает комментарий всем подписчикам его поста.
+// Publish никогда не блокируется: если буфер подписчика переполнен,
+// подписка закрывается, и клиент получает закрытый канал
+func (h *Hub) Publish(comment *models.Comment) {
+	var slow []*Subscription
+
+	h.mu.RLock()
+	for sub := range h.subs[comment.PostID] {
+		select {
+		case sub.ch <- comment:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	h.mu.RUnlock()
+
+	// Отключаем медленных подписчиков уже без блокировки на чтение
+	for _, sub := range slow {
+		sub.Close()
+	}
+}
+
+// SubscriberCount возвращает количество активных подписчиков поста
+func (h *Hub) SubscriberCount(postID string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.subs[postID])
+}
+
+// C возвращает канал событий подписки. Канал закрывается при отписке
+func (s *Subscription) C() <-chan *models.Comment {
+	return s.ch
+}
+
+// Close отписывает клиента и закрывает канал. Повторный вызов безопасен
+func (s *Subscription) Close() {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+
+	delete(h.subs[s.postID], s)
+	// Удаляем пустую группу, чтобы не копить ключи удаленных постов
+	if len(h.subs[s.postID]) == 0 {
+		delete(h.subs, s.postID)
+	}
+	close(s.ch)
+}
@@ -0,0 +1,71 @@
+package pubsub
+
+import (
+	"graphql-comments/internal/models"
+	"testing"
+)
+
+func TestHub_PublishToPostSubscribers(t *testing.T) {
+	hub := NewHub(4)
+
+	// 1. Два подписчика на один пост и один на другой
+	sub1 := hub.Subscribe("post_1")
+	sub2 := hub.Subscribe("post_1")
+	other := hub.Subscribe("post_2")
+
+	// 2. Публикуем комментарий к post_1
+	hub.Publish(&models.Comment{ID: "comment_1", PostID: "post_1"})
+
+	// 3. Оба подписчика post_1 получили событие
+	for _, sub := range []*Subscription{sub1, sub2} {
+		select {
+		case comment := <-sub.C():
+			if comment.ID != "comment_1" {
+				t.Errorf("Ожидали comment_1, получили %s", comment.ID)
+			}
+		default:
+			t.Errorf("Подписчик не получил событие")
+		}
+	}
+
+	// 4. Подписчик другого поста ничего не получил
+	select {
+	case <-other.C():
+		t.Errorf("Подписчик post_2 не должен получать события post_1")
+	default:
+	}
+}
+
+func TestHub_SlowConsumerIsDropped(t *testing.T) {
+	hub := NewHub(1)
+	sub := hub.Subscribe("post_1")
+
+	// Первое событие ложится в буфер, второе переполняет его
+	hub.Publish(&models.Comment{ID: "comment_1", PostID: "post_1"})
+	hub.Publish(&models.Comment{ID: "comment_2", PostID: "post_1"})
+
+	if hub.SubscriberCount("post_1") != 0 {
+		t.Errorf("Медленный подписчик должен быть отключен")
+	}
+
+	// В канале остается то, что успело попасть в буфер, затем канал закрыт
+	<-sub.C()
+	if _, ok := <-sub.C(); ok {
+		t.Errorf("Ожидали закрытый канал")
+	}
+}
+
+func TestHub_CloseCleansUp(t *testing.T) {
+	hub := NewHub(1)
+	sub := hub.Subscribe("post_1")
+
+	sub.Close()
+	sub.Close() // повторное закрытие безопасно
+
+	if hub.SubscriberCount("post_1") != 0 {
+		t.Errorf("Ожидали 0 подписчиков после отписки")
+	}
+	if len(hub.subs) != 0 {
+		t.Errorf("Ожидали удаление пустой группы подписчиков")
+	}
+}
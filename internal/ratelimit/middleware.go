@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Middleware ограничивает HTTP запросы клиента лимитом Requests и кладет IP клиента
+// в контекст для проверок в резолверах. Ставится после auth.Middleware,
+// чтобы аутентифицированные клиенты ограничивались по пользователю, а не по IP
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(WithClientIP(r.Context(), l.clientIP(r)))
+
+		if err := l.allowClient(r.Context(), "requests", l.limits.Requests); err != nil {
+			writeRateLimited(w, err.(*Error))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP возвращает IP адрес клиента. X-Forwarded-For может подставить сам клиент,
+// поэтому заголовок читается только от доверенных прокси: справа налево,
+// пока адреса принадлежат доверенным сетям. Первый чужой адрес - клиент
+func (l *Limiter) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if len(l.trustedProxies) == 0 || !l.trusted(host) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			// Испорченный заголовок: дальше доверенного прокси не идем
+			break
+		}
+		host = hop
+		if !l.trusted(hop) {
+			break
+		}
+	}
+	return host
+}
+
+// trusted сообщает, что адрес принадлежит доверенному прокси
+func (l *Limiter) trusted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, proxy := range l.trustedProxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeRateLimited отвечает 429 с заголовком Retry-After и ошибкой в формате GraphQL
+func writeRateLimited(w http.ResponseWriter, err *Error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(err.retryAfterSeconds()))
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]interface{}{{
+			"message":    err.Error(),
+			"extensions": err.Extensions(),
+		}},
+	})
+}
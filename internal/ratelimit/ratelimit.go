@@ -0,0 +1,211 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"graphql-comments/internal/auth"
+)
+
+// CodeRateLimited - код ошибки в extensions.code GraphQL ответа
+const CodeRateLimited = "RATE_LIMITED"
+
+// Limit - емкость корзины токенов: не больше Burst операций за Interval.
+// Токены восполняются равномерно, по одному за Interval/Burst.
+// Нулевой Limit - без ограничения
+type Limit struct {
+	Burst    int
+	Interval time.Duration
+}
+
+// ParseLimit разбирает лимит вида "10/1m" (10 операций в минуту); "" и "0" - без ограничения
+func ParseLimit(value string) (Limit, error) {
+	if value == "" || value == "0" {
+		return Limit{}, nil
+	}
+
+	burst, interval, found := strings.Cut(value, "/")
+	if !found {
+		return Limit{}, fmt.Errorf("лимит %q: ожидали формат число/интервал, например 10/1m", value)
+	}
+	n, err := strconv.Atoi(burst)
+	if err != nil || n < 0 {
+		return Limit{}, fmt.Errorf("лимит %q: неверное число операций", value)
+	}
+	d, err := time.ParseDuration(interval)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("лимит %q: неверный интервал", value)
+	}
+	return Limit{Burst: n, Interval: d}, nil
+}
+
+// Enabled сообщает, что лимит задан
+func (l Limit) Enabled() bool {
+	return l.Burst > 0 && l.Interval > 0
+}
+
+// emission - интервал восполнения одного токена
+func (l Limit) emission() time.Duration {
+	return l.Interval / time.Duration(l.Burst)
+}
+
+func (l Limit) String() string {
+	if !l.Enabled() {
+		return "без ограничения"
+	}
+	return fmt.Sprintf("%d/%s", l.Burst, l.Interval)
+}
+
+// Store хранит состояние корзин. Реализация в памяти подходит для одной реплики,
+// PostgresStore - для нескольких реплик за балансировщиком
+type Store interface {
+	// Allow забирает токен из корзины key. Если токенов нет, возвращает,
+	// через сколько появится следующий (0 - операция разрешена)
+	Allow(ctx context.Context, key string, limit Limit) (time.Duration, error)
+}
+
+// Error - превышен лимит. Реализует gqlerrors.ExtendedError,
+// поэтому клиент получает extensions.code и подсказку retryAfter в секундах
+type Error struct {
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("слишком много запросов, повторите через %d с", e.retryAfterSeconds())
+}
+
+// Extensions возвращает код ошибки и время до повтора
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":       CodeRateLimited,
+		"retryAfter": e.retryAfterSeconds(),
+	}
+}
+
+// retryAfterSeconds округляет время до повтора вверх до целых секунд (как в заголовке Retry-After)
+func (e *Error) retryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// Limits - лимиты по видам операций
+type Limits struct {
+	// Requests - HTTP запросы одного клиента (Middleware)
+	Requests Limit
+	// Posts и Comments - создание постов и комментариев одним клиентом
+	Posts    Limit
+	Comments Limit
+	// PostComments - комментарии в один пост от всех клиентов вместе
+	PostComments Limit
+}
+
+// Limiter применяет лимиты к клиентам и постам. Клиент - пользователь запроса,
+// а для анонимного запроса - IP адрес
+type Limiter struct {
+	store  Store
+	limits Limits
+	// trustedProxies - сети прокси, чьему X-Forwarded-For верит Middleware
+	trustedProxies []netip.Prefix
+}
+
+// New создает ограничитель с хранилищем состояния store
+func New(store Store, limits Limits) *Limiter {
+	return &Limiter{store: store, limits: limits}
+}
+
+// TrustProxies задает сети прокси (балансировщиков), стоящих перед сервером: за ними IP клиента
+// берется из X-Forwarded-For. Без них все клиенты за прокси делили бы одну корзину.
+// Вызывается до начала обработки запросов
+func (l *Limiter) TrustProxies(proxies []netip.Prefix) {
+	l.trustedProxies = proxies
+}
+
+// ParseTrustedProxies разбирает список сетей через запятую: CIDR (10.0.0.0/8) или отдельные адреса
+func ParseTrustedProxies(value string) ([]netip.Prefix, error) {
+	var proxies []netip.Prefix
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if strings.Contains(item, "/") {
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, fmt.Errorf("доверенный прокси %q: неверная сеть", item)
+			}
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(item)
+		if err != nil {
+			return nil, fmt.Errorf("доверенный прокси %q: неверный адрес", item)
+		}
+		proxies = append(proxies, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return proxies, nil
+}
+
+// AllowPost проверяет лимит на создание постов клиентом
+func (l *Limiter) AllowPost(ctx context.Context) error {
+	return l.allowClient(ctx, "posts", l.limits.Posts)
+}
+
+// AllowComment проверяет лимиты на комментарии клиента и на комментарии в пост
+func (l *Limiter) AllowComment(ctx context.Context, postID string) error {
+	if err := l.allowClient(ctx, "comments", l.limits.Comments); err != nil {
+		return err
+	}
+	return l.allow(ctx, "post:"+postID, l.limits.PostComments)
+}
+
+// allowClient проверяет лимит клиента запроса. Клиента может не быть
+// (запрос не через HTTP) - тогда ограничивать некого
+func (l *Limiter) allowClient(ctx context.Context, kind string, limit Limit) error {
+	client := clientKey(ctx)
+	if client == "" {
+		return nil
+	}
+	return l.allow(ctx, kind+":"+client, limit)
+}
+
+// allow забирает токен из корзины. Ошибка хранилища не должна останавливать запись,
+// поэтому она только логируется и операция разрешается
+func (l *Limiter) allow(ctx context.Context, key string, limit Limit) error {
+	if !limit.Enabled() {
+		return nil
+	}
+
+	retryAfter, err := l.store.Allow(ctx, key, limit)
+	if err != nil {
+		log.Printf("Ошибка ограничителя запросов для %s, пропускаем: %v", key, err)
+		return nil
+	}
+	if retryAfter > 0 {
+		return &Error{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// clientIPKey - ключ контекста, под которым Middleware хранит IP клиента
+type clientIPKey struct{}
+
+// WithClientIP добавляет IP клиента в контекст
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// clientKey возвращает ключ клиента: пользователь или IP (пусто, если клиент неизвестен)
+func clientKey(ctx context.Context) string {
+	if viewer := auth.ViewerFromContext(ctx); viewer != nil {
+		return "user:" + viewer.ID
+	}
+	if ip, _ := ctx.Value(clientIPKey{}).(string); ip != "" {
+		return "ip:" + ip
+	}
+	return ""
+}
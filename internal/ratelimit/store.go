@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+)
+
+// Корзины хранятся в виде GCRA: вместо числа токенов - теоретическое время прихода (tat)
+// следующей операции. Каждая операция сдвигает tat на Interval/Burst, операция разрешена,
+// пока tat опережает текущее время не больше чем на Interval. Это та же корзина токенов,
+// но состояние - одно время, которое легко обновить одним запросом к БД
+
+// sweepInterval - как часто MemoryStore удаляет полностью восполненные корзины
+const sweepInterval = time.Minute
+
+// MemoryStore хранит корзины в памяти процесса
+type MemoryStore struct {
+	mu        sync.Mutex
+	tat       map[string]time.Time
+	lastSweep time.Time
+	// now - часы, подменяются в тестах
+	now func() time.Time
+}
+
+// NewMemoryStore создает хранилище корзин в памяти
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tat: make(map[string]time.Time), now: time.Now}
+}
+
+// Allow реализует Store
+func (s *MemoryStore) Allow(ctx context.Context, key string, limit Limit) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	tat := s.tat[key]
+	if tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(limit.emission())
+	if allowAt := next.Add(-limit.Interval); allowAt.After(now) {
+		return allowAt.Sub(now), nil
+	}
+
+	s.tat[key] = next
+	return 0, nil
+}
+
+// sweep удаляет корзины, восполненные до конца: они не отличаются от отсутствующих
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, tat := range s.tat {
+		if !tat.After(now) {
+			delete(s.tat, key)
+		}
+	}
+}
+
+// PostgresStore хранит корзины в таблице rate_limits (миграция 0006), поэтому
+// лимиты общие для всех реплик. Время берется из БД, чтобы не зависеть от часов реплик
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore создает хранилище корзин в PostgreSQL
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Allow реализует Store. Проверка и сдвиг tat выполняются одним запросом:
+// если токена нет, условие WHERE не дает обновить строку
+func (s *PostgresStore) Allow(ctx context.Context, key string, limit Limit) (time.Duration, error) {
+	emission, interval := limit.emission().Seconds(), limit.Interval.Seconds()
+
+	query := `INSERT INTO rate_limits AS r (key, tat) VALUES ($1, now() + make_interval(secs => $2))
+		ON CONFLICT (key) DO UPDATE SET tat = GREATEST(r.tat, now()) + make_interval(secs => $2)
+			WHERE GREATEST(r.tat, now()) + make_interval(secs => $2) - make_interval(secs => $3) <= now()
+		RETURNING tat`
+	var tat time.Time
+	err := s.db.QueryRowContext(ctx, query, key, emission, interval).Scan(&tat)
+	if err == nil {
+		return 0, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	// Токена нет - считаем, когда он появится
+	var retryAfter float64
+	retryQuery := `SELECT EXTRACT(EPOCH FROM GREATEST(tat, now()) + make_interval(secs => $2)
+		- make_interval(secs => $3) - now()) FROM rate_limits WHERE key = $1`
+	if err := s.db.QueryRowContext(ctx, retryQuery, key, emission, interval).Scan(&retryAfter); err != nil {
+		return 0, err
+	}
+	// Токен мог появиться между запросами - все равно просим подождать минимально
+	return max(time.Duration(retryAfter*float64(time.Second)), time.Millisecond), nil
+}
+
+// DeleteExpired удаляет полностью восполненные корзины
+func (s *PostgresStore) DeleteExpired(ctx context.Context) (int, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM rate_limits WHERE tat <= now()`)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	return int(deleted), err
+}
+
+// RunCleanup периодически удаляет восполненные корзины, пока не отменен ctx
+func (s *PostgresStore) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.DeleteExpired(ctx); err != nil {
+				log.Printf("Ошибка очистки корзин ограничителя запросов: %v", err)
+			}
+		}
+	}
+}
+
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*PostgresStore)(nil)
+)
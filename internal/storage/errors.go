@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// Общие ошибки хранилища. Обе реализации возвращают одни и те же ошибки,
+// поэтому вызывающий код проверяет их через errors.Is, не завися от бэкенда
+var (
+	// ErrNotFound - запись не найдена
+	ErrNotFound = errors.New("запись не найдена")
+	// ErrAlreadyExists - запись с таким ID уже существует
+	ErrAlreadyExists = errors.New("запись уже существует")
+	// ErrParentNotFound - родительский комментарий не найден
+	ErrParentNotFound = errors.New("родительский комментарий не найден")
+	// ErrConflict возвращается, если запись изменили после того, как клиент ее прочитал
+	// (переданная версия не совпадает с текущей)
+	ErrConflict = errors.New("запись была изменена, версия устарела")
+	// ErrCommentsDisabled возвращается при попытке прокомментировать пост,
+	// у которого отключены комментарии
+	ErrCommentsDisabled = errors.New("комментарии к посту отключены")
+	// ErrCommentDeleted возвращается при попытке изменить удаленный комментарий
+	// или ответить на него
+	ErrCommentDeleted = errors.New("комментарий удален")
+	// ErrThreadLocked возвращается при ответе в ветку, закрытую модератором
+	ErrThreadLocked = errors.New("ветка обсуждения закрыта")
+	// ErrUserBanned возвращается, если автору запрещено комментировать пост
+	ErrUserBanned = errors.New("пользователю запрещено комментировать этот пост")
+	// ErrNotPending возвращается при модерации комментария, который не ждет проверки
+	ErrNotPending = errors.New("комментарий не ожидает модерации")
+	// ErrEditFlagged возвращается, если фильтры отметили правку опубликованного комментария.
+	// Комментарий остается опубликованным с прежним текстом, чтобы не скрывать ветку его ответов
+	ErrEditFlagged = errors.New("правка не прошла фильтры модерации")
+)
+
+// Уточненные ошибки: свое сообщение, но errors.Is совпадает с общей ошибкой
+var (
+	ErrPostNotFound    = &kindError{message: "пост не найден", kind: ErrNotFound}
+	ErrCommentNotFound = &kindError{message: "комментарий не найден", kind: ErrNotFound}
+	ErrPostExists      = &kindError{message: "пост уже существует", kind: ErrAlreadyExists}
+	ErrCommentExists   = &kindError{message: "комментарий уже существует", kind: ErrAlreadyExists}
+	errCursorNotFound  = &kindError{message: "курсор не найден", kind: ErrNotFound}
+)
+
+// kindError - ошибка с собственным сообщением, относящаяся к одной из общих ошибок
+type kindError struct {
+	message string
+	kind    error
+}
+
+func (e *kindError) Error() string {
+	return e.message
+}
+
+func (e *kindError) Unwrap() error {
+	return e.kind
+}
+
+// Коды ошибок PostgreSQL, которые приводятся к ошибкам хранилища
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+)
+
+// mapPQError приводит ошибки ограничений PostgreSQL к ошибкам хранилища.
+// Остальные ошибки возвращаются как есть
+func mapPQError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code {
+	case pqUniqueViolation:
+		switch pqErr.Table {
+		case "posts":
+			return ErrPostExists
+		case "comments":
+			return ErrCommentExists
+		}
+		return ErrAlreadyExists
+
+	case pqForeignKeyViolation:
+		switch pqErr.Constraint {
+		case "comments_parent_id_fkey":
+			return ErrParentNotFound
+		case "comments_post_id_fkey", "post_bans_post_id_fkey":
+			return ErrPostNotFound
+		}
+		return ErrNotFound
+	}
+
+	return err
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestMapPQError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{&pq.Error{Code: pqUniqueViolation, Table: "posts"}, ErrAlreadyExists},
+		{&pq.Error{Code: pqForeignKeyViolation, Constraint: "comments_parent_id_fkey"}, ErrParentNotFound},
+		{&pq.Error{Code: pqForeignKeyViolation, Constraint: "comments_post_id_fkey"}, ErrNotFound},
+	}
+	for _, tt := range tests {
+		if got := mapPQError(tt.err); !errors.Is(got, tt.want) {
+			t.Errorf("mapPQError(%v) = %v, ожидали %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryStorage_NotFoundErrors(t *testing.T) {
+	store := NewMemoryStorage()
+	ctx := context.Background()
+
+	if _, err := store.GetPost(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Ожидали ErrNotFound для поста, получили %v", err)
+	}
+	if _, err := store.GetComment(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Ожидали ErrNotFound для комментария, получили %v", err)
+	}
+}
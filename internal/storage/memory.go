@@ -1,9 +1,11 @@
 package storage
 
 import (
-	"errors"
+	"context"
 	"graphql-comments/internal/models"
+	"sort"
 	"sync"
+	"time"
 )
 
 // MemoryStorage - реализация Storage, которая хранит данные в памяти
@@ -11,24 +13,52 @@ type MemoryStorage struct {
 	mu       sync.RWMutex               
 	posts    map[string]*models.Post    
 	comments map[string]*models.Comment 
+
+	// История правок комментариев: ID комментария -> предыдущие версии
+	revisions map[string][]*models.CommentRevision
+
+	// Голоса и реакции: ID комментария -> ID пользователя -> запись
+	reactions map[string]map[string]*models.Reaction
+
+	// Запреты комментировать: ID поста -> ID пользователя -> запрет
+	bans map[string]map[string]*models.PostBan
+	// Журнал действий модераторов в порядке записи
+	audit []*models.AuditEntry
+
+	// Вторичные индексы, ID комментариев в порядке создания:
+	// ID поста -> все его комментарии, ID комментария -> прямые ответы
+	byPost   map[string][]string
+	byParent map[string][]string
+
+	// Порядковые номера вставки - нужны для стабильной сортировки при пагинации
+	seq        int64
+	postSeq    map[string]int64
+	commentSeq map[string]int64
 }
 
 // NewMemoryStorage создает новый экземпляр MemoryStorage
 func NewMemoryStorage() *MemoryStorage {
 	return &MemoryStorage{
-		posts:    make(map[string]*models.Post),
-		comments: make(map[string]*models.Comment),
+		posts:      make(map[string]*models.Post),
+		comments:   make(map[string]*models.Comment),
+		revisions:  make(map[string][]*models.CommentRevision),
+		reactions:  make(map[string]map[string]*models.Reaction),
+		bans:       make(map[string]map[string]*models.PostBan),
+		byPost:     make(map[string][]string),
+		byParent:   make(map[string][]string),
+		postSeq:    make(map[string]int64),
+		commentSeq: make(map[string]int64),
 	}
 }
 
 // CreatePost создает новый пост
-func (s *MemoryStorage) CreatePost(post *models.Post) error {
+func (s *MemoryStorage) CreatePost(ctx context.Context, post *models.Post) error {
 	s.mu.Lock()        
 	defer s.mu.Unlock() 
 
 	// Проверяем, существует ли уже пост
 	if _, exists := s.posts[post.ID]; exists {
-		return errors.New("пост уже существует")
+		return ErrPostExists
 	}
 
 	// Инициализируем Comments слайс
@@ -36,19 +66,27 @@ func (s *MemoryStorage) CreatePost(post *models.Post) error {
 		post.Comments = []*models.Comment{}
 	}
 
-	// Сохраняем пост в мапе
-	s.posts[post.ID] = post
+	// Проставляем время создания, как DEFAULT now() в PostgreSQL
+	post.CreatedAt = time.Now().UTC()
+	post.UpdatedAt = post.CreatedAt
+	post.Version = 1
+
+	// Сохраняем копию, переданный пост остается у вызывающего кода
+	stored := *post
+	s.posts[post.ID] = &stored
+	s.seq++
+	s.postSeq[post.ID] = s.seq
 	return nil
 }
 
 // GetPost возвращает пост по ID
-func (s *MemoryStorage) GetPost(id string) (*models.Post, error) {
+func (s *MemoryStorage) GetPost(ctx context.Context, id string) (*models.Post, error) {
 	s.mu.RLock()        
 	defer s.mu.RUnlock() 
 
 	post, exists := s.posts[id]
 	if !exists {
-		return nil, errors.New("пост не найден")
+		return nil, ErrPostNotFound
 	}
 
 	// Создаем копию поста
@@ -62,7 +100,7 @@ func (s *MemoryStorage) GetPost(id string) (*models.Post, error) {
 }
 
 // GetAllPosts возвращает все посты
-func (s *MemoryStorage) GetAllPosts() ([]*models.Post, error) {
+func (s *MemoryStorage) GetAllPosts(ctx context.Context) ([]*models.Post, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -82,37 +120,115 @@ func (s *MemoryStorage) GetAllPosts() ([]*models.Post, error) {
 }
 
 // DeletePost удаляет пост по ID
-func (s *MemoryStorage) DeletePost(id string) error {
+func (s *MemoryStorage) DeletePost(ctx context.Context, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if _, exists := s.posts[id]; !exists {
-		return errors.New("пост не найден")
+		return ErrPostNotFound
 	}
 
 	delete(s.posts, id)
+	delete(s.postSeq, id)
+
+	// Как ON DELETE CASCADE в PostgreSQL: вместе с постом удаляются
+	// все его комментарии, их история правок и реакции
+	for _, commentID := range s.byPost[id] {
+		delete(s.comments, commentID)
+		delete(s.commentSeq, commentID)
+		delete(s.revisions, commentID)
+		delete(s.reactions, commentID)
+		delete(s.byParent, commentID)
+	}
+	delete(s.byPost, id)
+	delete(s.bans, id)
 	return nil
 }
 
+// UpdatePost обновляет заголовок и/или текст поста, если версия совпадает
+func (s *MemoryStorage) UpdatePost(ctx context.Context, input *models.UpdatePostInput) (*models.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post, exists := s.posts[input.ID]
+	if !exists {
+		return nil, ErrPostNotFound
+	}
+
+	// Оптимистичная блокировка: клиент правит ту версию, которую видел
+	if post.Version != input.Version {
+		return nil, ErrConflict
+	}
+
+	if input.Title != nil {
+		post.Title = *input.Title
+	}
+	if input.Content != nil {
+		post.Content = *input.Content
+	}
+	post.Version++
+	post.UpdatedAt = time.Now().UTC()
+
+	postCopy := *post
+	return &postCopy, nil
+}
+
+// SetAllowComments включает или отключает комментарии к посту
+func (s *MemoryStorage) SetAllowComments(ctx context.Context, postID string, allow bool) (*models.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post, exists := s.posts[postID]
+	if !exists {
+		return nil, ErrPostNotFound
+	}
+
+	// Заменяем пост целиком: выданные раньше копии не меняются
+	updated := *post
+	updated.CommentsDisabled = !allow
+	updated.UpdatedAt = time.Now().UTC()
+	s.posts[postID] = &updated
+
+	postCopy := updated
+	return &postCopy, nil
+}
+
 // CreateComment создает новый комментарий
-func (s *MemoryStorage) CreateComment(comment *models.Comment) error {
+func (s *MemoryStorage) CreateComment(ctx context.Context, comment *models.Comment) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	// Проверяем существование комментария
 	if _, exists := s.comments[comment.ID]; exists {
-		return errors.New("комментарий уже существует")
+		return ErrCommentExists
 	}
 
 	// Проверяем, существует ли пост, к которому добавляем комментарий
-	if _, exists := s.posts[comment.PostID]; !exists {
-		return errors.New("пост не найден")
+	post, exists := s.posts[comment.PostID]
+	if !exists {
+		return ErrPostNotFound
+	}
+
+	// Проверяем, что комментарии к посту разрешены
+	if post.CommentsDisabled {
+		return ErrCommentsDisabled
+	}
+	if _, banned := s.bans[comment.PostID][comment.Author]; banned {
+		return ErrUserBanned
 	}
 
 	// Если есть ParentID, проверяем существование родительского комментария
 	if comment.ParentID != nil {
-		if _, exists := s.comments[*comment.ParentID]; !exists {
-			return errors.New("родительский комментарий не найден")
+		parent, exists := s.comments[*comment.ParentID]
+		// Комментарий на модерации снаружи не виден - отвечать на него нельзя
+		if !exists || parent.Status != models.CommentPublished {
+			return ErrParentNotFound
+		}
+		if parent.IsDeleted {
+			return ErrCommentDeleted
+		}
+		if s.threadLocked(parent) {
+			return ErrThreadLocked
 		}
 	}
 
@@ -121,83 +237,808 @@ func (s *MemoryStorage) CreateComment(comment *models.Comment) error {
 		comment.Replies = []*models.Comment{}
 	}
 
-	// Сохраняем комментарий
-	s.comments[comment.ID] = comment
+	// Проставляем время создания
+	comment.CreatedAt = time.Now().UTC()
+	comment.UpdatedAt = comment.CreatedAt
+	comment.Version = 1
+	if comment.Status == "" {
+		comment.Status = models.CommentPublished
+	}
+
+	// Сохраняем копию: голоса и модерация меняют хранимый комментарий под s.mu,
+	// а переданный остается у вызывающего кода
+	stored := *comment
+	s.comments[comment.ID] = &stored
+	s.seq++
+	s.commentSeq[comment.ID] = s.seq
+
+	// Добавление в конец сохраняет порядок создания в индексах
+	s.byPost[comment.PostID] = append(s.byPost[comment.PostID], comment.ID)
+	if comment.ParentID != nil {
+		s.byParent[*comment.ParentID] = append(s.byParent[*comment.ParentID], comment.ID)
+	}
 	return nil
 }
 
 // GetComment возвращает комментарий по ID
-func (s *MemoryStorage) GetComment(id string) (*models.Comment, error) {
+func (s *MemoryStorage) GetComment(ctx context.Context, id string) (*models.Comment, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	comment, exists := s.comments[id]
 	if !exists {
-		return nil, errors.New("комментарий не найден")
+		return nil, ErrCommentNotFound
 	}
 
 	// Создаем копию
-	commentCopy := *comment
-	if commentCopy.Replies == nil {
-		commentCopy.Replies = []*models.Comment{}
-	}
-
-	return &commentCopy, nil
+	return s.copyComment(comment), nil
 }
 
 // GetCommentsByPostID возвращает ВСЕ комментарии для указанного поста
-func (s *MemoryStorage) GetCommentsByPostID(postID string) ([]*models.Comment, error) {
+func (s *MemoryStorage) GetCommentsByPostID(ctx context.Context, postID string) ([]*models.Comment, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	// Проверяем существование поста
 	if _, exists := s.posts[postID]; !exists {
-		return nil, errors.New("пост не найден")
+		return nil, ErrPostNotFound
 	}
 
-	// Собираем все комментарии для этого поста
-	var comments []*models.Comment
-	for _, comment := range s.comments { // бежим по все мапе
-		if comment.PostID == postID {
-			// Создаем копию комментария
-			commentCopy := *comment
-			if commentCopy.Replies == nil {
-				commentCopy.Replies = []*models.Comment{}
-			}
-			comments = append(comments, &commentCopy)
-		}
+	// Собираем все комментарии для этого поста по индексу
+	ids := s.published(s.byPost[postID])
+	comments := make([]*models.Comment, 0, len(ids))
+	for _, id := range ids {
+		// Создаем копию комментария
+		comments = append(comments, s.copyComment(s.comments[id]))
 	}
 	// вОзвращаем плоским списком
 	return comments, nil
 }
 
+// GetCommentsByPostIDs возвращает комментарии нескольких постов.
+// Индекс уже хранит их в порядке создания, как в PostgresStorage
+func (s *MemoryStorage) GetCommentsByPostIDs(ctx context.Context, postIDs []string) (map[string][]*models.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string][]*models.Comment, len(postIDs))
+	for _, postID := range postIDs {
+		ids := s.published(s.byPost[postID])
+		if len(ids) == 0 {
+			continue
+		}
+
+		comments := make([]*models.Comment, 0, len(ids))
+		for _, id := range ids {
+			comments = append(comments, s.copyComment(s.comments[id]))
+		}
+		result[postID] = comments
+	}
+
+	return result, nil
+}
+
+// UpdateComment обновляет текст комментария, если версия совпадает,
+// и сохраняет предыдущий текст в истории правок
+func (s *MemoryStorage) UpdateComment(ctx context.Context, input *models.UpdateCommentInput) (*models.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment, exists := s.comments[input.ID]
+	if !exists {
+		return nil, ErrCommentNotFound
+	}
+
+	if comment.IsDeleted {
+		return nil, ErrCommentDeleted
+	}
+	if comment.Version != input.Version {
+		return nil, ErrConflict
+	}
+	if input.ModerationReason != "" && comment.Status == models.CommentPublished {
+		return nil, ErrEditFlagged
+	}
+
+	now := time.Now().UTC()
+	s.revisions[comment.ID] = append(s.revisions[comment.ID], &models.CommentRevision{
+		CommentID: comment.ID,
+		Version:   comment.Version,
+		Content:   comment.Content,
+		EditedAt:  now,
+	})
+
+	comment.Content = input.Content
+	comment.Version++
+	comment.UpdatedAt = now
+	if input.ModerationReason != "" {
+		comment.Status = models.CommentPending
+		comment.ModerationReason = input.ModerationReason
+	}
+
+	return s.copyComment(comment), nil
+}
+
+// GetCommentRevisions возвращает историю правок комментария (от старых к новым)
+func (s *MemoryStorage) GetCommentRevisions(ctx context.Context, commentID string) ([]*models.CommentRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.comments[commentID]; !exists {
+		return nil, ErrCommentNotFound
+	}
+
+	revisions := make([]*models.CommentRevision, 0, len(s.revisions[commentID]))
+	for _, revision := range s.revisions[commentID] {
+		revisionCopy := *revision
+		revisions = append(revisions, &revisionCopy)
+	}
+
+	return revisions, nil
+}
+
 // DeleteComment удаляет комментарий по ID и все его ответы рекурсивно
-func (s *MemoryStorage) DeleteComment(id string) error {
+func (s *MemoryStorage) DeleteComment(ctx context.Context, id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	// Проверяем существование комментария
 	if _, exists := s.comments[id]; !exists {
-		return errors.New("комментарий не найден")
+		return ErrCommentNotFound
+	}
+
+	// Удаляем комментарий вместе со всеми дочерними
+	s.deleteCommentTree(id)
+
+	return nil
+}
+
+// SoftDeleteComment помечает комментарий удаленным (повторный вызов ничего не меняет)
+func (s *MemoryStorage) SoftDeleteComment(ctx context.Context, id string) (*models.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment, exists := s.comments[id]
+	if !exists {
+		return nil, ErrCommentNotFound
+	}
+
+	if !comment.IsDeleted {
+		deletedAt := time.Now().UTC()
+		comment.IsDeleted = true
+		comment.DeletedAt = &deletedAt
+	}
+
+	return s.copyComment(comment), nil
+}
+
+// RestoreComment снимает с комментария пометку об удалении
+func (s *MemoryStorage) RestoreComment(ctx context.Context, id string) (*models.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment, exists := s.comments[id]
+	if !exists {
+		return nil, ErrCommentNotFound
+	}
+
+	comment.IsDeleted = false
+	comment.DeletedAt = nil
+
+	return s.copyComment(comment), nil
+}
+
+// PurgeDeletedComments окончательно удаляет старые удаленные комментарии без ответов
+func (s *MemoryStorage) PurgeDeletedComments(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Удалять можно только заглушки, на которые уже никто не отвечает
+	purgeable := func(id string) bool {
+		comment, exists := s.comments[id]
+		return exists && comment.IsDeleted && comment.DeletedAt.Before(before) && len(s.byParent[id]) == 0
+	}
+
+	var queue []string
+	for id := range s.comments {
+		if purgeable(id) {
+			queue = append(queue, id)
+		}
+	}
+
+	// Удаление листа может освободить его удаленного родителя - он встает в очередь
+	purged := 0
+	for len(queue) > 0 {
+		id := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+
+		parentID := s.comments[id].ParentID
+		s.deleteCommentTree(id)
+		purged++
+
+		if parentID != nil && purgeable(*parentID) {
+			queue = append(queue, *parentID)
+		}
+	}
+
+	return purged, nil
+}
+
+// deleteCommentTree удаляет комментарий и все его ответы.
+// Поддерево обходится по индексу ответов, поэтому время зависит от его размера,
+// а не от числа всех комментариев
+func (s *MemoryStorage) deleteCommentTree(id string) {
+	root := s.comments[id]
+
+	removed := make(map[string]bool)
+	stack := []string{id}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		removed[current] = true
+		stack = append(stack, s.byParent[current]...)
+	}
+
+	for commentID := range removed {
+		delete(s.comments, commentID)
+		delete(s.commentSeq, commentID)
+		delete(s.revisions, commentID)
+		delete(s.reactions, commentID)
+		delete(s.byParent, commentID)
+	}
+
+	// Из индексов выше поддерева убираем только его корень и потомков
+	s.byPost[root.PostID] = withoutIDs(s.byPost[root.PostID], removed)
+	if root.ParentID != nil {
+		s.byParent[*root.ParentID] = withoutIDs(s.byParent[*root.ParentID], removed)
+	}
+}
+
+// copyComment возвращает копию комментария для выдачи наружу
+// с пустыми ответами и числом прямых ответов из индекса. Вызывается под s.mu
+func (s *MemoryStorage) copyComment(comment *models.Comment) *models.Comment {
+	commentCopy := *comment
+	commentCopy.Replies = []*models.Comment{}
+	commentCopy.ReplyCount = len(s.published(s.byParent[comment.ID]))
+	return &commentCopy
+}
+
+// published оставляет из списка ID только опубликованные комментарии.
+// Обычно на модерации ничего нет, поэтому список копируется, только если есть что убрать.
+// Вызывается под s.mu
+func (s *MemoryStorage) published(ids []string) []string {
+	for i, id := range ids {
+		if s.comments[id].Status == models.CommentPublished {
+			continue
+		}
+		kept := append([]string{}, ids[:i]...)
+		for _, id := range ids[i+1:] {
+			if s.comments[id].Status == models.CommentPublished {
+				kept = append(kept, id)
+			}
+		}
+		return kept
+	}
+	return ids
+}
+
+// withoutIDs убирает из списка ID из removed, сохраняя порядок
+func withoutIDs(ids []string, removed map[string]bool) []string {
+	kept := ids[:0]
+	for _, id := range ids {
+		if !removed[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// GetCommentAncestors возвращает опубликованных предков комментария от корня к родителю
+func (s *MemoryStorage) GetCommentAncestors(ctx context.Context, id string, limit int) ([]*models.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	comment, exists := s.comments[id]
+	if !exists {
+		return nil, ErrCommentNotFound
+	}
+
+	// Идем от родителя вверх, затем разворачиваем
+	ancestors := []*models.Comment{}
+	for parentID := comment.ParentID; parentID != nil && (limit < 0 || len(ancestors) < limit); {
+		parent, exists := s.comments[*parentID]
+		if !exists || parent.PostID != comment.PostID || parent.Status != models.CommentPublished {
+			break
+		}
+		ancestors = append(ancestors, s.copyComment(parent))
+		parentID = parent.ParentID
+	}
+	for i, j := 0, len(ancestors)-1; i < j; i, j = i+1, j-1 {
+		ancestors[i], ancestors[j] = ancestors[j], ancestors[i]
+	}
+	return ancestors, nil
+}
+
+// GetCommentSubtree возвращает опубликованные ответы на комментарий на любой глубине в порядке создания
+func (s *MemoryStorage) GetCommentSubtree(ctx context.Context, id string) ([]*models.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.comments[id]; !exists {
+		return nil, ErrCommentNotFound
+	}
+
+	var ids []string
+	for level := s.published(s.byParent[id]); len(level) > 0; {
+		ids = append(ids, level...)
+		var next []string
+		for _, id := range level {
+			next = append(next, s.published(s.byParent[id])...)
+		}
+		level = next
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return s.commentSeq[ids[i]] < s.commentSeq[ids[j]]
+	})
+
+	subtree := make([]*models.Comment, 0, len(ids))
+	for _, id := range ids {
+		subtree = append(subtree, s.copyComment(s.comments[id]))
+	}
+	return subtree, nil
+}
+
+// GetPostsPage возвращает страницу постов, новые посты идут первыми
+func (s *MemoryStorage) GetPostsPage(ctx context.Context, page PageParams) (*PostPage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Упорядочиваем ID постов по убыванию порядкового номера
+	ids := make([]string, 0, len(s.posts))
+	for id := range s.posts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return s.postSeq[ids[i]] > s.postSeq[ids[j]]
+	})
+
+	start, end, err := pageBounds(ids, page)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PostPage{
+		Posts:           make([]*models.Post, 0, end-start),
+		HasPreviousPage: start > 0,
+		HasNextPage:     end < len(ids),
+		TotalCount:      len(ids),
+	}
+	for _, id := range ids[start:end] {
+		postCopy := *s.posts[id]
+		if postCopy.Comments == nil {
+			postCopy.Comments = []*models.Comment{}
+		}
+		result.Posts = append(result.Posts, &postCopy)
+	}
+
+	return result, nil
+}
+
+// GetCommentsPage возвращает страницу комментариев поста в порядке создания
+func (s *MemoryStorage) GetCommentsPage(ctx context.Context, postID string, page PageParams) (*CommentPage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Проверяем существование поста
+	if _, exists := s.posts[postID]; !exists {
+		return nil, ErrPostNotFound
+	}
+
+	// ID комментариев поста из индекса, уже в порядке создания
+	ids := s.published(s.byPost[postID])
+
+	start, end, err := pageBounds(ids, page)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CommentPage{
+		Comments:        make([]*models.Comment, 0, end-start),
+		HasPreviousPage: start > 0,
+		HasNextPage:     end < len(ids),
+		TotalCount:      len(ids),
+	}
+	for _, id := range ids[start:end] {
+		result.Comments = append(result.Comments, s.copyComment(s.comments[id]))
+	}
+
+	return result, nil
+}
+
+// GetCommentsUpToDepth возвращает комментарии поста не глубже maxDepth уровней.
+// Дерево обходится по уровням, поэтому глубокие ветки не просматриваются
+func (s *MemoryStorage) GetCommentsUpToDepth(ctx context.Context, postID string, maxDepth int) ([]*models.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.posts[postID]; !exists {
+		return nil, ErrPostNotFound
+	}
+
+	var result []*models.Comment
+
+	// Первый уровень - корневые комментарии поста и комментарии,
+	// чей родитель отсутствует в посте (их судьбу решает слой API)
+	var level []string
+	for _, id := range s.published(s.byPost[postID]) {
+		parentID := s.comments[id].ParentID
+		if parentID == nil {
+			level = append(level, id)
+			continue
+		}
+		if parent, exists := s.comments[*parentID]; !exists || parent.PostID != postID {
+			level = append(level, id)
+		}
 	}
 
-	// Рекурсивно удаляем все дочерние комментарии
-	s.deleteCommentRecursive(id)
+	for depth := 1; depth <= maxDepth && len(level) > 0; depth++ {
+		var next []string
+		for _, id := range level {
+			result = append(result, s.copyComment(s.comments[id]))
+			next = append(next, s.published(s.byParent[id])...)
+		}
+		level = next
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return s.commentSeq[result[i].ID] < s.commentSeq[result[j].ID]
+	})
+	return result, nil
+}
+
+// GetReplies возвращает страницу прямых ответов на комментарий в заданном порядке
+func (s *MemoryStorage) GetReplies(ctx context.Context, parentID string, order CommentOrder, page PageParams) (*CommentPage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.comments[parentID]; !exists {
+		return nil, ErrCommentNotFound
+	}
+
+	// Прямые ответы из индекса (в порядке создания), затем нужная сортировка
+	replyIDs := s.published(s.byParent[parentID])
+	replies := make([]*models.Comment, 0, len(replyIDs))
+	for _, id := range replyIDs {
+		replies = append(replies, s.copyComment(s.comments[id]))
+	}
+	SortComments(replies, order)
+
+	ids := make([]string, len(replies))
+	for i, reply := range replies {
+		ids[i] = reply.ID
+	}
+
+	start, end, err := pageBounds(ids, page)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CommentPage{
+		Comments:        replies[start:end],
+		HasPreviousPage: start > 0,
+		HasNextPage:     end < len(ids),
+		TotalCount:      len(ids),
+	}, nil
+}
 
+// VoteComment ставит голос пользователя и пересчитывает рейтинг комментария
+func (s *MemoryStorage) VoteComment(ctx context.Context, commentID, userID string, value int) (*models.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment, err := s.reactableComment(commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	reaction := s.reaction(commentID, userID)
+	comment.Score += value - reaction.Value
+	reaction.Value = value
+	reaction.UpdatedAt = time.Now().UTC()
+	s.dropEmptyReaction(reaction)
+
+	return s.copyComment(comment), nil
+}
+
+// ReactToComment ставит или снимает эмодзи-реакцию пользователя
+func (s *MemoryStorage) ReactToComment(ctx context.Context, commentID, userID string, emoji *string) (*models.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment, err := s.reactableComment(commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	reaction := s.reaction(commentID, userID)
+	reaction.Emoji = nil
+	if emoji != nil {
+		emojiCopy := *emoji
+		reaction.Emoji = &emojiCopy
+	}
+	reaction.UpdatedAt = time.Now().UTC()
+	s.dropEmptyReaction(reaction)
+
+	return s.copyComment(comment), nil
+}
+
+// GetReaction возвращает голос и реакцию пользователя на комментарий
+func (s *MemoryStorage) GetReaction(ctx context.Context, commentID, userID string) (*models.Reaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.comments[commentID]; !exists {
+		return nil, ErrCommentNotFound
+	}
+
+	reaction, exists := s.reactions[commentID][userID]
+	if !exists {
+		return &models.Reaction{CommentID: commentID, UserID: userID}, nil
+	}
+	reactionCopy := *reaction
+	return &reactionCopy, nil
+}
+
+// GetReactionCounts возвращает число реакций каждым эмодзи
+func (s *MemoryStorage) GetReactionCounts(ctx context.Context, commentID string) ([]*models.ReactionCount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.comments[commentID]; !exists {
+		return nil, ErrCommentNotFound
+	}
+	return s.reactionCounts(commentID), nil
+}
+
+// GetReactionCountsByCommentIDs возвращает число реакций нескольких комментариев
+func (s *MemoryStorage) GetReactionCountsByCommentIDs(ctx context.Context, commentIDs []string) (map[string][]*models.ReactionCount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string][]*models.ReactionCount, len(commentIDs))
+	for _, commentID := range commentIDs {
+		if counts := s.reactionCounts(commentID); len(counts) > 0 {
+			result[commentID] = counts
+		}
+	}
+	return result, nil
+}
+
+// GetReactionsByCommentIDs возвращает голоса и реакции пользователя на несколько комментариев
+func (s *MemoryStorage) GetReactionsByCommentIDs(ctx context.Context, commentIDs []string, userID string) (map[string]*models.Reaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]*models.Reaction)
+	for _, commentID := range commentIDs {
+		if reaction, exists := s.reactions[commentID][userID]; exists {
+			reactionCopy := *reaction
+			result[commentID] = &reactionCopy
+		}
+	}
+	return result, nil
+}
+
+// reactionCounts считает реакции на комментарий по эмодзи. Вызывается под s.mu
+func (s *MemoryStorage) reactionCounts(commentID string) []*models.ReactionCount {
+	byEmoji := make(map[string]int)
+	for _, reaction := range s.reactions[commentID] {
+		if reaction.Emoji != nil {
+			byEmoji[*reaction.Emoji]++
+		}
+	}
+
+	counts := make([]*models.ReactionCount, 0, len(byEmoji))
+	for emoji, count := range byEmoji {
+		counts = append(counts, &models.ReactionCount{Emoji: emoji, Count: count})
+	}
+	// Как ORDER BY count DESC, emoji в PostgreSQL
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Emoji < counts[j].Emoji
+	})
+	return counts
+}
+
+// reactableComment возвращает комментарий, на который можно реагировать
+func (s *MemoryStorage) reactableComment(commentID string) (*models.Comment, error) {
+	comment, exists := s.comments[commentID]
+	if !exists {
+		return nil, ErrCommentNotFound
+	}
+	if comment.IsDeleted {
+		return nil, ErrCommentDeleted
+	}
+	// Комментарии на проверке и отклоненные клиентам не видны
+	if comment.Status != models.CommentPublished {
+		return nil, ErrCommentNotFound
+	}
+	return comment, nil
+}
+
+// reaction возвращает запись пользователя, создавая пустую при необходимости
+func (s *MemoryStorage) reaction(commentID, userID string) *models.Reaction {
+	byUser, exists := s.reactions[commentID]
+	if !exists {
+		byUser = make(map[string]*models.Reaction)
+		s.reactions[commentID] = byUser
+	}
+	reaction, exists := byUser[userID]
+	if !exists {
+		reaction = &models.Reaction{CommentID: commentID, UserID: userID}
+		byUser[userID] = reaction
+	}
+	return reaction
+}
+
+// dropEmptyReaction удаляет запись без голоса и реакции, как это делает PostgreSQL
+func (s *MemoryStorage) dropEmptyReaction(reaction *models.Reaction) {
+	if reaction.Value != 0 || reaction.Emoji != nil {
+		return
+	}
+	delete(s.reactions[reaction.CommentID], reaction.UserID)
+	if len(s.reactions[reaction.CommentID]) == 0 {
+		delete(s.reactions, reaction.CommentID)
+	}
+}
+
+// HideComment скрывает комментарий или снимает скрытие
+func (s *MemoryStorage) HideComment(ctx context.Context, id string, hidden bool) (*models.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment, exists := s.comments[id]
+	if !exists {
+		return nil, ErrCommentNotFound
+	}
+
+	comment.IsHidden = hidden
+	return s.copyComment(comment), nil
+}
+
+// LockThread закрывает ветку комментария для новых ответов или открывает ее
+func (s *MemoryStorage) LockThread(ctx context.Context, id string, locked bool) (*models.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment, exists := s.comments[id]
+	if !exists {
+		return nil, ErrCommentNotFound
+	}
+
+	comment.IsLocked = locked
+	return s.copyComment(comment), nil
+}
+
+// threadLocked сообщает, что комментарий или один из его предков закрыт
+func (s *MemoryStorage) threadLocked(comment *models.Comment) bool {
+	for comment != nil {
+		if comment.IsLocked {
+			return true
+		}
+		if comment.ParentID == nil {
+			return false
+		}
+		comment = s.comments[*comment.ParentID]
+	}
+	return false
+}
+
+// BanUserFromPost запрещает пользователю комментировать пост
+func (s *MemoryStorage) BanUserFromPost(ctx context.Context, ban *models.PostBan) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.posts[ban.PostID]; !exists {
+		return ErrPostNotFound
+	}
+
+	byUser, exists := s.bans[ban.PostID]
+	if !exists {
+		byUser = make(map[string]*models.PostBan)
+		s.bans[ban.PostID] = byUser
+	}
+	ban.CreatedAt = time.Now().UTC()
+	banCopy := *ban
+	byUser[ban.UserID] = &banCopy
 	return nil
 }
 
-// deleteCommentRecursive удаляет комментарий и все его ответы
-func (s *MemoryStorage) deleteCommentRecursive(id string) {
-	// Удаляем текущий комментарий
-	delete(s.comments, id)
+// UnbanUserFromPost снимает запрет комментировать пост
+func (s *MemoryStorage) UnbanUserFromPost(ctx context.Context, postID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	// Ищем и удаляем все комментарии, у которых этот комментарий - родитель
-	for commentID, comment := range s.comments {
-		if comment.ParentID != nil && *comment.ParentID == id {
-			s.deleteCommentRecursive(commentID)
+	if _, banned := s.bans[postID][userID]; !banned {
+		return ErrNotFound
+	}
+	delete(s.bans[postID], userID)
+	return nil
+}
+
+// WithAudit выполняет fn и добавляет запись в журнал. Запись в памяти не может
+// не удаться, поэтому действие без записи в журнале не остается
+func (s *MemoryStorage) WithAudit(ctx context.Context, entry *models.AuditEntry, fn func(ctx context.Context) error) error {
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	return s.AddAuditEntry(ctx, entry)
+}
+
+// AddAuditEntry добавляет запись в журнал модерации
+func (s *MemoryStorage) AddAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.ID = int64(len(s.audit) + 1)
+	entry.CreatedAt = time.Now().UTC()
+	entryCopy := *entry
+	s.audit = append(s.audit, &entryCopy)
+	return nil
+}
+
+// GetAuditLog возвращает последние записи журнала модерации
+func (s *MemoryStorage) GetAuditLog(ctx context.Context, limit int) ([]*models.AuditEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]*models.AuditEntry, 0, limit)
+	for i := len(s.audit) - 1; i >= 0 && len(entries) < limit; i-- {
+		entryCopy := *s.audit[i]
+		entries = append(entries, &entryCopy)
+	}
+	return entries, nil
+}
+
+// GetModerationQueue возвращает комментарии, ожидающие проверки, в порядке создания
+func (s *MemoryStorage) GetModerationQueue(ctx context.Context, postID string, limit int) ([]*models.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var pending []*models.Comment
+	for _, comment := range s.comments {
+		if comment.Status == models.CommentPending && (postID == "" || comment.PostID == postID) {
+			pending = append(pending, comment)
 		}
 	}
+	sort.Slice(pending, func(i, j int) bool {
+		return s.commentSeq[pending[i].ID] < s.commentSeq[pending[j].ID]
+	})
+
+	queue := make([]*models.Comment, 0, min(limit, len(pending)))
+	for _, comment := range pending[:min(limit, len(pending))] {
+		queue = append(queue, s.copyComment(comment))
+	}
+	return queue, nil
+}
+
+// SetCommentStatus переводит комментарий из очереди модерации в статус status
+func (s *MemoryStorage) SetCommentStatus(ctx context.Context, id string, status models.CommentStatus) (*models.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment, exists := s.comments[id]
+	if !exists {
+		return nil, ErrCommentNotFound
+	}
+	if comment.Status != models.CommentPending {
+		return nil, ErrNotPending
+	}
+
+	comment.Status = status
+	return s.copyComment(comment), nil
 }
 
 var _ Storage = (*MemoryStorage)(nil)
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"graphql-comments/internal/models"
+	"testing"
+)
+
+// Размеры хранилища для бенчмарков: время поиска по индексу
+// не должно расти вместе с общим числом комментариев
+var benchSizes = []int{1_000, 10_000, 100_000}
+
+// commentsPerPost - комментариев в каждом посте бенчмарка
+const commentsPerPost = 10
+
+// newBenchStorage заполняет хранилище total комментариями по commentsPerPost на пост
+func newBenchStorage(b *testing.B, total int) *MemoryStorage {
+	b.Helper()
+	store := NewMemoryStorage()
+	ctx := context.Background()
+
+	for i := 0; i < total/commentsPerPost; i++ {
+		postID := fmt.Sprintf("post_%d", i)
+		store.CreatePost(ctx, &models.Post{ID: postID, Title: "Пост", Content: "Текст"})
+		for j := 0; j < commentsPerPost; j++ {
+			comment := &models.Comment{ID: fmt.Sprintf("comment_%d_%d", i, j), PostID: postID, Content: "Текст"}
+			if err := store.CreateComment(ctx, comment); err != nil {
+				b.Fatalf("Ошибка создания комментария: %v", err)
+			}
+		}
+	}
+
+	return store
+}
+
+func BenchmarkMemoryStorage_GetCommentsByPostID(b *testing.B) {
+	for _, total := range benchSizes {
+		b.Run(fmt.Sprintf("comments=%d", total), func(b *testing.B) {
+			store := newBenchStorage(b, total)
+			ctx := context.Background()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := store.GetCommentsByPostID(ctx, "post_0"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMemoryStorage_GetReplies(b *testing.B) {
+	for _, total := range benchSizes {
+		b.Run(fmt.Sprintf("comments=%d", total), func(b *testing.B) {
+			store := newBenchStorage(b, total)
+			ctx := context.Background()
+			parentID := "comment_0_0"
+			store.CreateComment(ctx, &models.Comment{ID: "reply", PostID: "post_0", ParentID: &parentID, Content: "Ответ"})
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := store.GetReplies(ctx, parentID, DefaultCommentOrder, PageParams{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkMemoryStorage_DeleteDeepThread удаляет цепочку ответов глубины depth
+// среди 100 000 посторонних комментариев
+func BenchmarkMemoryStorage_DeleteDeepThread(b *testing.B) {
+	for _, depth := range []int{10, 100, 1_000} {
+		b.Run(fmt.Sprintf("depth=%d", depth), func(b *testing.B) {
+			store := newBenchStorage(b, 100_000)
+			ctx := context.Background()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				var parentID *string
+				for d := 0; d < depth; d++ {
+					id := fmt.Sprintf("thread_%d", d)
+					store.CreateComment(ctx, &models.Comment{ID: id, PostID: "post_0", ParentID: parentID, Content: "Ответ"})
+					parentID = &id
+				}
+				b.StartTimer()
+
+				if err := store.DeleteComment(ctx, "thread_0"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -1,12 +1,16 @@
 package storage
 
 import (
+	"context"
+	"errors"
 	"graphql-comments/internal/models"
 	"testing"
+	"time"
 )
 
 func TestMemoryStorage_CreateAndGetPost(t *testing.T) {
 	store := NewMemoryStorage()
+	ctx := context.Background()
 
 	// 1. Создаем пост
 	post := &models.Post{
@@ -15,13 +19,13 @@ func TestMemoryStorage_CreateAndGetPost(t *testing.T) {
 		Content: "Текст поста",
 	}
 
-	err := store.CreatePost(post)
+	err := store.CreatePost(ctx, post)
 	if err != nil {
 		t.Errorf("Ошибка при создании поста: %v", err)
 	}
 
 	// 2. Получаем пост
-	saved, err := store.GetPost("post_1")
+	saved, err := store.GetPost(ctx, "post_1")
 	if err != nil {
 		t.Errorf("Ошибка при получении поста: %v", err)
 	}
@@ -34,9 +38,10 @@ func TestMemoryStorage_CreateAndGetPost(t *testing.T) {
 
 func TestMemoryStorage_CreateAndGetComment(t *testing.T) {
 	store := NewMemoryStorage()
+	ctx := context.Background()
 
 	// 1. Сначала создаем пост (обязательно)
-	store.CreatePost(&models.Post{ID: "post_1", Title: "Пост", Content: "Контент"})
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Контент"})
 
 	// 2. Создаем комментарий
 	comment := &models.Comment{
@@ -45,13 +50,13 @@ func TestMemoryStorage_CreateAndGetComment(t *testing.T) {
 		Content: "Мой комментарий",
 	}
 
-	err := store.CreateComment(comment)
+	err := store.CreateComment(ctx, comment)
 	if err != nil {
 		t.Errorf("Ошибка при создании комментария: %v", err)
 	}
 
 	// 3. Получаем комментарии поста
-	comments, err := store.GetCommentsByPostID("post_1")
+	comments, err := store.GetCommentsByPostID(ctx, "post_1")
 	if err != nil {
 		t.Errorf("Ошибка при получении комментариев: %v", err)
 	}
@@ -65,3 +70,176 @@ func TestMemoryStorage_CreateAndGetComment(t *testing.T) {
 		t.Errorf("Ожидали 'Мой комментарий', получили '%s'", comments[0].Content)
 	}
 }
+
+func TestMemoryStorage_GetCommentsPage(t *testing.T) {
+	store := NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Контент"})
+
+	// 1. Создаем 5 комментариев
+	ids := []string{"c1", "c2", "c3", "c4", "c5"}
+	for _, id := range ids {
+		store.CreateComment(ctx, &models.Comment{ID: id, PostID: "post_1", Content: "Текст"})
+	}
+
+	// 2. Первая страница из двух комментариев
+	first := 2
+	page, err := store.GetCommentsPage(ctx, "post_1", PageParams{First: &first})
+	if err != nil {
+		t.Fatalf("Ошибка при получении страницы: %v", err)
+	}
+	if len(page.Comments) != 2 || page.Comments[0].ID != "c1" || page.Comments[1].ID != "c2" {
+		t.Fatalf("Ожидали c1, c2, получили %v", page.Comments)
+	}
+	if !page.HasNextPage || page.HasPreviousPage || page.TotalCount != 5 {
+		t.Errorf("Неверные признаки страницы: %+v", page)
+	}
+
+	// 3. Следующая страница после c2
+	after := "c2"
+	page, err = store.GetCommentsPage(ctx, "post_1", PageParams{First: &first, After: &after})
+	if err != nil {
+		t.Fatalf("Ошибка при получении страницы: %v", err)
+	}
+	if len(page.Comments) != 2 || page.Comments[0].ID != "c3" || !page.HasPreviousPage {
+		t.Errorf("Ожидали страницу c3, c4, получили %v", page.Comments)
+	}
+
+	// 4. Последние два перед c5
+	last := 2
+	before := "c5"
+	page, err = store.GetCommentsPage(ctx, "post_1", PageParams{Last: &last, Before: &before})
+	if err != nil {
+		t.Fatalf("Ошибка при получении страницы: %v", err)
+	}
+	if len(page.Comments) != 2 || page.Comments[0].ID != "c3" || page.Comments[1].ID != "c4" {
+		t.Errorf("Ожидали c3, c4, получили %v", page.Comments)
+	}
+	if !page.HasNextPage || !page.HasPreviousPage {
+		t.Errorf("Неверные признаки страницы: %+v", page)
+	}
+}
+
+func TestMemoryStorage_CommentsDisabled(t *testing.T) {
+	store := NewMemoryStorage()
+	ctx := context.Background()
+
+	// 1. Создаем пост с отключенными комментариями
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Контент", CommentsDisabled: true})
+
+	// 2. Комментарий должен быть отклонен
+	err := store.CreateComment(ctx, &models.Comment{ID: "comment_1", PostID: "post_1", Content: "Текст"})
+	if !errors.Is(err, ErrCommentsDisabled) {
+		t.Fatalf("Ожидали ErrCommentsDisabled, получили %v", err)
+	}
+
+	// 3. Включаем комментарии - теперь создание проходит
+	if _, err := store.SetAllowComments(ctx, "post_1", true); err != nil {
+		t.Fatalf("Ошибка при включении комментариев: %v", err)
+	}
+	if err := store.CreateComment(ctx, &models.Comment{ID: "comment_1", PostID: "post_1", Content: "Текст"}); err != nil {
+		t.Errorf("Ошибка при создании комментария: %v", err)
+	}
+}
+
+func TestMemoryStorage_GetCommentsUpToDepth(t *testing.T) {
+	store := NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Контент"})
+
+	// 1. Цепочка из трех уровней: c1 <- c2 <- c3
+	c1, c2 := "c1", "c2"
+	store.CreateComment(ctx, &models.Comment{ID: "c1", PostID: "post_1", Content: "Корневой"})
+	store.CreateComment(ctx, &models.Comment{ID: "c2", PostID: "post_1", ParentID: &c1, Content: "Ответ"})
+	store.CreateComment(ctx, &models.Comment{ID: "c3", PostID: "post_1", ParentID: &c2, Content: "Ответ на ответ"})
+
+	// 2. Два уровня - третий не загружается
+	comments, err := store.GetCommentsUpToDepth(ctx, "post_1", 2)
+	if err != nil {
+		t.Fatalf("Ошибка при получении комментариев: %v", err)
+	}
+	if len(comments) != 2 || comments[0].ID != "c1" || comments[1].ID != "c2" {
+		t.Errorf("Ожидали c1, c2, получили %v", comments)
+	}
+
+	// 3. Прямые ответы на c1
+	replies, err := store.GetReplies(ctx, "c1", DefaultCommentOrder, PageParams{})
+	if err != nil {
+		t.Fatalf("Ошибка при получении ответов: %v", err)
+	}
+	if len(replies.Comments) != 1 || replies.Comments[0].ID != "c2" {
+		t.Errorf("Ожидали ответ c2, получили %v", replies.Comments)
+	}
+}
+
+func TestMemoryStorage_UpdatePostConflict(t *testing.T) {
+	store := NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Текст"})
+
+	title := "Новый заголовок"
+	updated, err := store.UpdatePost(ctx, &models.UpdatePostInput{ID: "post_1", Title: &title, Version: 1})
+	if err != nil {
+		t.Fatalf("Ошибка обновления поста: %v", err)
+	}
+	if updated.Title != title || updated.Content != "Текст" || updated.Version != 2 {
+		t.Errorf("Неожиданный результат обновления: %+v", updated)
+	}
+
+	// Второй клиент правит по старой версии
+	_, err = store.UpdatePost(ctx, &models.UpdatePostInput{ID: "post_1", Title: &title, Version: 1})
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("Ожидали ErrConflict, получили %v", err)
+	}
+}
+
+func TestMemoryStorage_SoftDeleteAndPurge(t *testing.T) {
+	store := NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Текст"})
+	parentID := "comment_1"
+	store.CreateComment(ctx, &models.Comment{ID: "comment_1", PostID: "post_1", Content: "Родитель"})
+	store.CreateComment(ctx, &models.Comment{ID: "comment_2", PostID: "post_1", ParentID: &parentID, Content: "Ответ"})
+
+	deleted, err := store.SoftDeleteComment(ctx, "comment_1")
+	if err != nil || !deleted.IsDeleted || deleted.DeletedAt == nil {
+		t.Fatalf("Ожидали заглушку удаленного комментария, получили %+v, %v", deleted, err)
+	}
+
+	// На удаленный комментарий отвечать нельзя
+	err = store.CreateComment(ctx, &models.Comment{ID: "comment_3", PostID: "post_1", ParentID: &parentID, Content: "Еще ответ"})
+	if !errors.Is(err, ErrCommentDeleted) {
+		t.Errorf("Ожидали ErrCommentDeleted, получили %v", err)
+	}
+
+	// Пока на заглушку есть ответ, очистка ее не трогает
+	future := time.Now().Add(time.Hour)
+	if purged, _ := store.PurgeDeletedComments(ctx, future); purged != 0 {
+		t.Errorf("Ожидали 0 удаленных комментариев, получили %d", purged)
+	}
+
+	// После удаления ответа уходят оба: ответ, затем освободившийся родитель
+	store.SoftDeleteComment(ctx, "comment_2")
+	if purged, _ := store.PurgeDeletedComments(ctx, future); purged != 2 {
+		t.Errorf("Ожидали 2 удаленных комментария, получили %d", purged)
+	}
+	if _, err := store.GetComment(ctx, "comment_1"); err == nil {
+		t.Errorf("Комментарий comment_1 должен быть удален окончательно")
+	}
+}
+
+func TestMemoryStorage_RestoreComment(t *testing.T) {
+	store := NewMemoryStorage()
+	ctx := context.Background()
+	store.CreatePost(ctx, &models.Post{ID: "post_1", Title: "Пост", Content: "Текст"})
+	store.CreateComment(ctx, &models.Comment{ID: "comment_1", PostID: "post_1", Content: "Текст"})
+
+	store.SoftDeleteComment(ctx, "comment_1")
+	restored, err := store.RestoreComment(ctx, "comment_1")
+	if err != nil {
+		t.Fatalf("Ошибка восстановления: %v", err)
+	}
+	if restored.IsDeleted || restored.DeletedAt != nil || restored.Content != "Текст" {
+		t.Errorf("Ожидали восстановленный комментарий с исходным текстом, получили %+v", restored)
+	}
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"sort"
+
+	"graphql-comments/internal/models"
+)
+
+// CommentOrderField - поле, по которому упорядочиваются комментарии
+type CommentOrderField string
+
+const (
+	// OrderByCreatedAt - по времени создания
+	OrderByCreatedAt CommentOrderField = "CREATED_AT"
+	// OrderByScore - по рейтингу комментария
+	OrderByScore CommentOrderField = "SCORE"
+	// OrderByReplyCount - по числу прямых ответов
+	OrderByReplyCount CommentOrderField = "REPLY_COUNT"
+)
+
+// CommentOrder - порядок комментариев на одном уровне дерева.
+// При равных значениях поля комментарии идут по времени создания в том же направлении
+type CommentOrder struct {
+	Field CommentOrderField
+	Desc  bool
+}
+
+// DefaultCommentOrder - хронологический порядок, старые комментарии первыми
+var DefaultCommentOrder = CommentOrder{Field: OrderByCreatedAt}
+
+// column возвращает колонку PostgreSQL для поля сортировки
+// (пустая строка - только created_at, id)
+func (o CommentOrder) column() string {
+	switch o.Field {
+	case OrderByScore:
+		return "score"
+	case OrderByReplyCount:
+		return "reply_count"
+	default:
+		return ""
+	}
+}
+
+// key возвращает значение поля сортировки комментария
+func (o CommentOrder) key(comment *models.Comment) int {
+	switch o.Field {
+	case OrderByScore:
+		return comment.Score
+	case OrderByReplyCount:
+		return comment.ReplyCount
+	default:
+		return 0
+	}
+}
+
+// SortComments упорядочивает комментарии одного уровня.
+// Ожидает список в порядке создания: он служит для разрешения равенств
+func SortComments(comments []*models.Comment, order CommentOrder) {
+	if order.Field == OrderByCreatedAt || order.Field == "" {
+		if order.Desc {
+			for i, j := 0, len(comments)-1; i < j; i, j = i+1, j-1 {
+				comments[i], comments[j] = comments[j], comments[i]
+			}
+		}
+		return
+	}
+
+	// Стабильная сортировка сохраняет порядок создания при равных ключах,
+	// для обратного порядка разворачиваем список заранее
+	if order.Desc {
+		SortComments(comments, CommentOrder{Field: OrderByCreatedAt, Desc: true})
+	}
+	sort.SliceStable(comments, func(i, j int) bool {
+		if order.Desc {
+			return order.key(comments[i]) > order.key(comments[j])
+		}
+		return order.key(comments[i]) < order.key(comments[j])
+	})
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"testing"
+
+	"graphql-comments/internal/models"
+)
+
+func TestSortComments(t *testing.T) {
+	newComments := func() []*models.Comment {
+		// В порядке создания; у c1 и c3 одинаковый рейтинг
+		return []*models.Comment{
+			{ID: "c1", Score: 5},
+			{ID: "c2", Score: 1},
+			{ID: "c3", Score: 5},
+		}
+	}
+	ids := func(comments []*models.Comment) []string {
+		result := make([]string, len(comments))
+		for i, comment := range comments {
+			result[i] = comment.ID
+		}
+		return result
+	}
+
+	tests := []struct {
+		name  string
+		order CommentOrder
+		want  []string
+	}{
+		{"CreatedAtAsc", DefaultCommentOrder, []string{"c1", "c2", "c3"}},
+		{"CreatedAtDesc", CommentOrder{Field: OrderByCreatedAt, Desc: true}, []string{"c3", "c2", "c1"}},
+		// При равном рейтинге порядок по времени создания в том же направлении
+		{"ScoreAsc", CommentOrder{Field: OrderByScore}, []string{"c2", "c1", "c3"}},
+		{"ScoreDesc", CommentOrder{Field: OrderByScore, Desc: true}, []string{"c3", "c1", "c2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comments := newComments()
+			SortComments(comments, tt.order)
+			got := ids(comments)
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Ожидали %v, получили %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package storage
+
+import "graphql-comments/internal/models"
+
+// PageParams - параметры курсорной пагинации в стиле Relay.
+// After и Before содержат ID элемента, от которого отсчитывается страница.
+// Одновременно задается только одно из First/Last.
+type PageParams struct {
+	First  *int
+	After  *string
+	Last   *int
+	Before *string
+}
+
+// PostPage - страница постов
+type PostPage struct {
+	Posts           []*models.Post
+	HasNextPage     bool
+	HasPreviousPage bool
+	TotalCount      int
+}
+
+// CommentPage - страница комментариев поста
+type CommentPage struct {
+	Comments        []*models.Comment
+	HasNextPage     bool
+	HasPreviousPage bool
+	TotalCount      int
+}
+
+// pageBounds вычисляет границы страницы [start, end) в упорядоченном списке ID
+func pageBounds(ids []string, page PageParams) (start, end int, err error) {
+	start, end = 0, len(ids)
+
+	// Сужаем окно по курсорам
+	if page.After != nil {
+		idx := indexOf(ids, *page.After)
+		if idx < 0 {
+			return 0, 0, errCursorNotFound
+		}
+		start = idx + 1
+	}
+	if page.Before != nil {
+		idx := indexOf(ids, *page.Before)
+		if idx < 0 {
+			return 0, 0, errCursorNotFound
+		}
+		end = idx
+	}
+	if end < start {
+		end = start
+	}
+
+	// Отрезаем нужное количество с начала или с конца окна
+	if page.First != nil && end-start > *page.First {
+		end = start + *page.First
+	} else if page.Last != nil && end-start > *page.Last {
+		start = end - *page.Last
+	}
+
+	return start, end, nil
+}
+
+// indexOf возвращает позицию ID в списке или -1
+func indexOf(ids []string, id string) int {
+	for i, v := range ids {
+		if v == id {
+			return i
+		}
+	}
+	return -1
+}
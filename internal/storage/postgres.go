@@ -1,11 +1,24 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"graphql-comments/internal/idgen"
 	"graphql-comments/internal/models"
+	"strings"
+	"time"
 
-	_ "github.com/lib/pq" // Драйвер PostgreSQL
+	"github.com/lib/pq" // Драйвер PostgreSQL
+)
+
+// Списки колонок, которые читаются в models.Post и models.Comment
+const (
+	postColumns    = `id, title, content, allow_comments, author, created_at, updated_at, version`
+	commentColumns = `id, post_id, parent_id, content, author, created_at, updated_at, version, deleted_at, score, reply_count, hidden_at, locked_at, status, moderation_reason`
+
+	// publishedFilter - условие, отсекающее комментарии на модерации и отклоненные
+	publishedFilter = `status = 'PUBLISHED'`
 )
 
 // PostgresStorage реализация Storage для PostgreSQL
@@ -28,62 +41,391 @@ func NewPostgresStorage(dataSourceName string) (*PostgresStorage, error) {
 	return &PostgresStorage{db: db}, nil
 }
 
+// DB возвращает пул соединений (нужен для миграций схемы)
+func (s *PostgresStorage) DB() *sql.DB {
+	return s.db
+}
+
+// querier - общие методы *sql.DB и *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// txKey - ключ контекста, под которым WithAudit передает свою транзакцию
+type txKey struct{}
+
+// conn возвращает транзакцию WithAudit, если операция выполняется внутри нее, иначе пул соединений
+func (s *PostgresStorage) conn(ctx context.Context) querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return s.db
+}
+
+// WithAudit выполняет fn и запись журнала в одной транзакции: операции хранилища
+// с контекстом fn идут через нее. UpdateComment и реакции открывают свою транзакцию
+// и внутри fn не вызываются
+func (s *PostgresStorage) WithAudit(ctx context.Context, entry *models.AuditEntry, fn func(ctx context.Context) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	txCtx := context.WithValue(ctx, txKey{}, tx)
+	if err := fn(txCtx); err != nil {
+		return err
+	}
+	if err := s.AddAuditEntry(txCtx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 // Close закрывает подключение к БД
 func (s *PostgresStorage) Close() error {
 	return s.db.Close()
 }
 
+// NewID выдает ID из последовательности PostgreSQL (post_id_seq, comment_id_seq).
+// Реализует idgen.Generator, поэтому ID не повторяются между перезапусками и репликами
+func (s *PostgresStorage) NewID(ctx context.Context, kind idgen.Kind) (string, error) {
+	var sequence string
+	switch kind {
+	case idgen.KindPost:
+		sequence = "post_id_seq"
+	case idgen.KindComment:
+		sequence = "comment_id_seq"
+	default:
+		return "", fmt.Errorf("unknown id kind: %s", kind)
+	}
+
+	var n int64
+	if err := s.conn(ctx).QueryRowContext(ctx, `SELECT nextval($1)`, sequence).Scan(&n); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s_%d", kind, n), nil
+}
+
 // CreatePost создает новый пост в БД
-func (s *PostgresStorage) CreatePost(post *models.Post) error {
-	query := `INSERT INTO posts (id, title, content) VALUES ($1, $2, $3)`
-	_, err := s.db.Exec(query, post.ID, post.Title, post.Content)
-	return err
+func (s *PostgresStorage) CreatePost(ctx context.Context, post *models.Post) error {
+	query := `INSERT INTO posts (id, title, content, allow_comments, author) VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at, updated_at, version`
+	err := s.conn(ctx).QueryRowContext(ctx, query, post.ID, post.Title, post.Content, !post.CommentsDisabled, post.Author).
+		Scan(&post.CreatedAt, &post.UpdatedAt, &post.Version)
+	// Дубликат ID приходит нарушением первичного ключа
+	return mapPQError(err)
 }
 
 // GetPost возвращает пост по ID из БД
-func (s *PostgresStorage) GetPost(id string) (*models.Post, error) {
-	query := `SELECT id, title, content FROM posts WHERE id = $1`
-	row := s.db.QueryRow(query, id)
-
-	post := &models.Post{}
-	err := row.Scan(&post.ID, &post.Title, &post.Content)
+func (s *PostgresStorage) GetPost(ctx context.Context, id string) (*models.Post, error) {
+	query := `SELECT ` + postColumns + ` FROM posts WHERE id = $1`
+	post, err := scanPost(s.conn(ctx).QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("post not found")
+		return nil, ErrPostNotFound
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	post.Comments = []*models.Comment{}
 	return post, nil
 }
 
 // GetAllPosts возвращает все посты из БД
-func (s *PostgresStorage) GetAllPosts() ([]*models.Post, error) {
-	query := `SELECT id, title, content FROM posts ORDER BY created_at DESC`
-	rows, err := s.db.Query(query)
+func (s *PostgresStorage) GetAllPosts(ctx context.Context) ([]*models.Post, error) {
+	query := `SELECT ` + postColumns + ` FROM posts ORDER BY created_at DESC`
+	return s.queryPosts(ctx, query)
+}
+
+// DeletePost удаляет пост по ID из БД
+func (s *PostgresStorage) DeletePost(ctx context.Context, id string) error {
+	query := `DELETE FROM posts WHERE id = $1`
+	result, err := s.conn(ctx).ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrPostNotFound
+	}
+
+	return nil
+}
+
+// UpdatePost обновляет заголовок и/или текст поста в БД, если версия совпадает
+func (s *PostgresStorage) UpdatePost(ctx context.Context, input *models.UpdatePostInput) (*models.Post, error) {
+	query := `UPDATE posts
+		SET title = COALESCE($2, title), content = COALESCE($3, content),
+			version = version + 1, updated_at = now()
+		WHERE id = $1 AND version = $4
+		RETURNING ` + postColumns
+	post, err := scanPost(s.conn(ctx).QueryRowContext(ctx, query, input.ID, input.Title, input.Content, input.Version))
+	if err == sql.ErrNoRows {
+		// Ничего не обновили - либо поста нет, либо версия устарела
+		var exists bool
+		if err := s.conn(ctx).QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM posts WHERE id = $1)`, input.ID).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrPostNotFound
+		}
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return post, nil
+}
+
+// SetAllowComments включает или отключает комментарии к посту в БД
+func (s *PostgresStorage) SetAllowComments(ctx context.Context, postID string, allow bool) (*models.Post, error) {
+	query := `UPDATE posts SET allow_comments = $2, updated_at = now() WHERE id = $1 RETURNING ` + postColumns
+	post, err := scanPost(s.conn(ctx).QueryRowContext(ctx, query, postID, allow))
+	if err == sql.ErrNoRows {
+		return nil, ErrPostNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return post, nil
+}
+
+// threadLockedQuery проверяет, закрыт ли комментарий $3 или один из его предков
+const threadLockedQuery = `WITH RECURSIVE up AS (
+		SELECT id, parent_id, locked_at FROM comments WHERE id = $3
+		UNION ALL
+		SELECT c.id, c.parent_id, c.locked_at FROM comments c JOIN up ON c.id = up.parent_id
+	)
+	SELECT 1 FROM up WHERE locked_at IS NOT NULL`
+
+// CreateComment создает новый комментарий в БД.
+// Вставка выполняется только если у поста разрешены комментарии и автор не забанен,
+// поэтому проверка и запись атомарны
+func (s *PostgresStorage) CreateComment(ctx context.Context, comment *models.Comment) error {
+	var query string
+	var args []interface{}
+
+	if comment.Status == "" {
+		comment.Status = models.CommentPublished
+	}
+
+	if comment.ParentID != nil {
+		// На удаленный, неопубликованный комментарий и в закрытую ветку отвечать нельзя;
+		// несуществующего родителя отсечет внешний ключ
+		query = `INSERT INTO comments (id, post_id, parent_id, content, author, status, moderation_reason)
+			SELECT $1, $2, $3, $4, $5, $6, $7 FROM posts WHERE id = $2 AND allow_comments
+				AND NOT EXISTS (SELECT 1 FROM post_bans WHERE post_id = $2 AND user_id = $5)
+				AND NOT EXISTS (SELECT 1 FROM comments WHERE id = $3
+					AND (deleted_at IS NOT NULL OR status <> 'PUBLISHED'))
+				AND NOT EXISTS (` + threadLockedQuery + `)
+			RETURNING created_at, updated_at, version`
+		args = []interface{}{comment.ID, comment.PostID, *comment.ParentID, comment.Content, comment.Author,
+			string(comment.Status), comment.ModerationReason}
+	} else {
+		query = `INSERT INTO comments (id, post_id, content, author, status, moderation_reason)
+			SELECT $1, $2, $3, $4, $5, $6 FROM posts WHERE id = $2 AND allow_comments
+				AND NOT EXISTS (SELECT 1 FROM post_bans WHERE post_id = $2 AND user_id = $4)
+			RETURNING created_at, updated_at, version`
+		args = []interface{}{comment.ID, comment.PostID, comment.Content, comment.Author,
+			string(comment.Status), comment.ModerationReason}
+	}
+
+	err := s.conn(ctx).QueryRowContext(ctx, query, args...).Scan(&comment.CreatedAt, &comment.UpdatedAt, &comment.Version)
+	if err != nil && err != sql.ErrNoRows {
+		// Дубликат ID или несуществующий родитель приходят нарушением ограничений
+		return mapPQError(err)
+	}
+
+	// Ничего не вставили - выясняем, какое из условий не выполнилось
+	if err == sql.ErrNoRows {
+		var allowComments, banned bool
+		postQuery := `SELECT allow_comments, EXISTS(SELECT 1 FROM post_bans WHERE post_id = $1 AND user_id = $2)
+			FROM posts WHERE id = $1`
+		err := s.conn(ctx).QueryRowContext(ctx, postQuery, comment.PostID, comment.Author).Scan(&allowComments, &banned)
+		if err == sql.ErrNoRows {
+			return ErrPostNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !allowComments {
+			return ErrCommentsDisabled
+		}
+		if banned {
+			return ErrUserBanned
+		}
+		// Условия поста уже выполняются - их изменили параллельно со вставкой
+		if comment.ParentID == nil {
+			return ErrConflict
+		}
+
+		var parentDeleted, parentPublished bool
+		parentQuery := `SELECT deleted_at IS NOT NULL, ` + publishedFilter + ` FROM comments WHERE id = $1`
+		err = s.conn(ctx).QueryRowContext(ctx, parentQuery, *comment.ParentID).Scan(&parentDeleted, &parentPublished)
+		if err == sql.ErrNoRows {
+			return ErrParentNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !parentPublished {
+			return ErrParentNotFound
+		}
+		if parentDeleted {
+			return ErrCommentDeleted
+		}
+		return ErrThreadLocked
+	}
+
+	return nil
+}
+
+// GetComment возвращает комментарий по ID из БД
+func (s *PostgresStorage) GetComment(ctx context.Context, id string) (*models.Comment, error) {
+	query := `SELECT ` + commentColumns + ` FROM comments WHERE id = $1`
+	comment, err := scanComment(s.conn(ctx).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+// GetCommentsByPostID возвращает все комментарии для поста из БД
+func (s *PostgresStorage) GetCommentsByPostID(ctx context.Context, postID string) ([]*models.Comment, error) {
+	// Как и in-memory хранилище, для несуществующего поста возвращаем ошибку, а не пустой список
+	var exists bool
+	if err := s.conn(ctx).QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM posts WHERE id = $1)`, postID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrPostNotFound
+	}
+
+	query := `SELECT ` + commentColumns + ` FROM comments WHERE post_id = $1 AND ` + publishedFilter + `
+		ORDER BY created_at, id`
+	return s.queryComments(ctx, query, postID)
+}
+
+// GetCommentsByPostIDs возвращает комментарии нескольких постов одним запросом
+func (s *PostgresStorage) GetCommentsByPostIDs(ctx context.Context, postIDs []string) (map[string][]*models.Comment, error) {
+	query := `SELECT ` + commentColumns + ` FROM comments WHERE post_id = ANY($1) AND ` + publishedFilter + `
+		ORDER BY created_at, id`
+	comments, err := s.queryComments(ctx, query, pq.Array(postIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]*models.Comment, len(postIDs))
+	for _, comment := range comments {
+		result[comment.PostID] = append(result[comment.PostID], comment)
+	}
+
+	return result, nil
+}
+
+// UpdateComment обновляет текст комментария в БД, если версия совпадает.
+// Предыдущий текст сохраняется в comment_revisions в той же транзакции
+func (s *PostgresStorage) UpdateComment(ctx context.Context, input *models.UpdateCommentInput) (*models.Comment, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Блокируем строку, чтобы параллельная правка дождалась нас
+	var version int
+	var content string
+	var status models.CommentStatus
+	var deletedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, `SELECT version, content, status, deleted_at FROM comments WHERE id = $1 FOR UPDATE`, input.ID).
+		Scan(&version, &content, &status, &deletedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deletedAt.Valid {
+		return nil, ErrCommentDeleted
+	}
+	if version != input.Version {
+		return nil, ErrConflict
+	}
+	if input.ModerationReason != "" && status == models.CommentPublished {
+		return nil, ErrEditFlagged
+	}
+
+	revisionQuery := `INSERT INTO comment_revisions (comment_id, version, content) VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, revisionQuery, input.ID, version, content); err != nil {
+		return nil, err
+	}
+
+	// Отмеченная фильтрами правка неопубликованного комментария возвращает его на проверку
+	updateQuery := `UPDATE comments SET content = $2, version = version + 1, updated_at = now(),
+			status = CASE WHEN $3 = '' THEN status ELSE 'PENDING' END,
+			moderation_reason = CASE WHEN $3 = '' THEN moderation_reason ELSE $3 END
+		WHERE id = $1 RETURNING ` + commentColumns
+	comment, err := scanComment(tx.QueryRowContext(ctx, updateQuery, input.ID, input.Content, input.ModerationReason))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// GetCommentRevisions возвращает историю правок комментария из БД (от старых к новым)
+func (s *PostgresStorage) GetCommentRevisions(ctx context.Context, commentID string) ([]*models.CommentRevision, error) {
+	var exists bool
+	if err := s.conn(ctx).QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM comments WHERE id = $1)`, commentID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrCommentNotFound
+	}
+
+	query := `SELECT comment_id, version, content, edited_at FROM comment_revisions
+		WHERE comment_id = $1 ORDER BY version`
+	rows, err := s.conn(ctx).QueryContext(ctx, query, commentID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var posts []*models.Post
+	revisions := []*models.CommentRevision{}
 	for rows.Next() {
-		post := &models.Post{}
-		if err := rows.Scan(&post.ID, &post.Title, &post.Content); err != nil {
+		revision := &models.CommentRevision{}
+		if err := rows.Scan(&revision.CommentID, &revision.Version, &revision.Content, &revision.EditedAt); err != nil {
 			return nil, err
 		}
-		post.Comments = []*models.Comment{}
-		posts = append(posts, post)
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return posts, nil
+	return revisions, nil
 }
 
-// DeletePost удаляет пост по ID из БД
-func (s *PostgresStorage) DeletePost(id string) error {
-	query := `DELETE FROM posts WHERE id = $1`
-	result, err := s.db.Exec(query, id)
+// DeleteComment удаляет комментарий по ID из БД
+func (s *PostgresStorage) DeleteComment(ctx context.Context, id string) error {
+	query := `DELETE FROM comments WHERE id = $1`
+	result, err := s.conn(ctx).ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -94,40 +436,259 @@ func (s *PostgresStorage) DeletePost(id string) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("post not found")
+		return ErrCommentNotFound
 	}
 
 	return nil
 }
 
-// CreateComment создает новый комментарий в БД
-func (s *PostgresStorage) CreateComment(comment *models.Comment) error {
-	var query string
-	var args []interface{}
+// SoftDeleteComment помечает комментарий удаленным (время первого удаления сохраняется)
+func (s *PostgresStorage) SoftDeleteComment(ctx context.Context, id string) (*models.Comment, error) {
+	query := `UPDATE comments SET deleted_at = COALESCE(deleted_at, now())
+		WHERE id = $1 RETURNING ` + commentColumns
+	comment, err := scanComment(s.conn(ctx).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	if comment.ParentID != nil {
-		query = `INSERT INTO comments (id, post_id, parent_id, content) VALUES ($1, $2, $3, $4)`
-		args = []interface{}{comment.ID, comment.PostID, *comment.ParentID, comment.Content}
-	} else {
-		query = `INSERT INTO comments (id, post_id, content) VALUES ($1, $2, $3)`
-		args = []interface{}{comment.ID, comment.PostID, comment.Content}
+	return comment, nil
+}
+
+// RestoreComment снимает с комментария пометку об удалении
+func (s *PostgresStorage) RestoreComment(ctx context.Context, id string) (*models.Comment, error) {
+	query := `UPDATE comments SET deleted_at = NULL WHERE id = $1 RETURNING ` + commentColumns
+	comment, err := scanComment(s.conn(ctx).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	_, err := s.db.Exec(query, args...)
-	return err
+	return comment, nil
 }
 
-// GetComment возвращает комментарий по ID из БД
-func (s *PostgresStorage) GetComment(id string) (*models.Comment, error) {
-	query := `SELECT id, post_id, parent_id, content FROM comments WHERE id = $1`
-	row := s.db.QueryRow(query, id)
+// PurgeDeletedComments окончательно удаляет старые удаленные комментарии без ответов.
+// Удаление листа может освободить его удаленного родителя, поэтому повторяем до нуля
+func (s *PostgresStorage) PurgeDeletedComments(ctx context.Context, before time.Time) (int, error) {
+	query := `DELETE FROM comments c
+		WHERE c.deleted_at < $1
+			AND NOT EXISTS (SELECT 1 FROM comments r WHERE r.parent_id = c.id)`
+
+	purged := 0
+	for {
+		result, err := s.conn(ctx).ExecContext(ctx, query, before)
+		if err != nil {
+			return purged, err
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return purged, err
+		}
+		if rowsAffected == 0 {
+			return purged, nil
+		}
+		purged += int(rowsAffected)
+	}
+}
+
+// GetPostsPage возвращает страницу постов из БД, новые посты идут первыми
+func (s *PostgresStorage) GetPostsPage(ctx context.Context, page PageParams) (*PostPage, error) {
+	result := &PostPage{}
+	if err := s.conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM posts`).Scan(&result.TotalCount); err != nil {
+		return nil, err
+	}
+
+	query, args, err := s.buildPageQuery(ctx,
+		`SELECT `+postColumns+` FROM posts`, "posts", "", nil, "", false, page)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Posts, err = s.queryPosts(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Posts, result.HasPreviousPage, result.HasNextPage = trimPage(result.Posts, page)
+	return result, nil
+}
+
+// GetCommentsPage возвращает страницу комментариев поста из БД в порядке создания
+func (s *PostgresStorage) GetCommentsPage(ctx context.Context, postID string, page PageParams) (*CommentPage, error) {
+	var exists bool
+	if err := s.conn(ctx).QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM posts WHERE id = $1)`, postID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrPostNotFound
+	}
+
+	result := &CommentPage{}
+	countQuery := `SELECT COUNT(*) FROM comments WHERE post_id = $1 AND ` + publishedFilter
+	if err := s.conn(ctx).QueryRowContext(ctx, countQuery, postID).Scan(&result.TotalCount); err != nil {
+		return nil, err
+	}
+
+	query, args, err := s.buildPageQuery(ctx,
+		`SELECT `+commentColumns+` FROM comments`, "comments",
+		"post_id = $1 AND "+publishedFilter, []interface{}{postID}, "", true, page)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Comments, err = s.queryComments(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Comments, result.HasPreviousPage, result.HasNextPage = trimPage(result.Comments, page)
+	return result, nil
+}
+
+// GetCommentsUpToDepth возвращает комментарии поста не глубже maxDepth уровней.
+// Обход дерева выполняется рекурсивным CTE с ограничением глубины,
+// комментарии без родителя в посте считаются первым уровнем
+func (s *PostgresStorage) GetCommentsUpToDepth(ctx context.Context, postID string, maxDepth int) ([]*models.Comment, error) {
+	query := `
+		WITH RECURSIVE tree AS (
+			SELECT ` + commentColumns + `, 1 AS depth
+			FROM comments c
+			WHERE post_id = $1 AND ` + publishedFilter + ` AND (parent_id IS NULL OR NOT EXISTS (
+				SELECT 1 FROM comments p WHERE p.id = c.parent_id AND p.post_id = c.post_id))
+			UNION ALL
+			SELECT c.id, c.post_id, c.parent_id, c.content, c.author, c.created_at, c.updated_at, c.version, c.deleted_at, c.score, c.reply_count, c.hidden_at, c.locked_at, c.status, c.moderation_reason, t.depth + 1
+			FROM comments c
+			JOIN tree t ON c.parent_id = t.id
+			WHERE t.depth < $2 AND c.status = 'PUBLISHED'
+		)
+		SELECT ` + commentColumns + ` FROM tree ORDER BY created_at, id`
+
+	return s.queryComments(ctx, query, postID, maxDepth)
+}
+
+// GetCommentAncestors возвращает опубликованных предков комментария рекурсивным CTE вверх по parent_id,
+// не глубже limit уровней (limit < 0 - до корня)
+func (s *PostgresStorage) GetCommentAncestors(ctx context.Context, id string, limit int) ([]*models.Comment, error) {
+	if err := s.commentExists(ctx, id); err != nil {
+		return nil, err
+	}
+
+	query := `
+		WITH RECURSIVE chain AS (
+			SELECT p.id, p.post_id, p.parent_id, p.content, p.author, p.created_at, p.updated_at, p.version, p.deleted_at, p.score, p.reply_count, p.hidden_at, p.locked_at, p.status, p.moderation_reason, 1 AS depth
+			FROM comments c
+			JOIN comments p ON p.id = c.parent_id AND p.post_id = c.post_id
+			WHERE c.id = $1 AND p.status = 'PUBLISHED' AND ($2 < 0 OR $2 > 0)
+			UNION ALL
+			SELECT p.id, p.post_id, p.parent_id, p.content, p.author, p.created_at, p.updated_at, p.version, p.deleted_at, p.score, p.reply_count, p.hidden_at, p.locked_at, p.status, p.moderation_reason, a.depth + 1
+			FROM comments p
+			JOIN chain a ON p.id = a.parent_id AND p.post_id = a.post_id
+			WHERE p.status = 'PUBLISHED' AND ($2 < 0 OR a.depth < $2)
+		)
+		SELECT ` + commentColumns + ` FROM chain ORDER BY depth DESC`
+
+	return s.queryComments(ctx, query, id, limit)
+}
 
+// GetCommentSubtree возвращает опубликованные ответы на комментарий рекурсивным CTE вниз по parent_id
+func (s *PostgresStorage) GetCommentSubtree(ctx context.Context, id string) ([]*models.Comment, error) {
+	if err := s.commentExists(ctx, id); err != nil {
+		return nil, err
+	}
+
+	query := `
+		WITH RECURSIVE tree AS (
+			SELECT ` + commentColumns + `
+			FROM comments
+			WHERE parent_id = $1 AND ` + publishedFilter + `
+			UNION ALL
+			SELECT c.id, c.post_id, c.parent_id, c.content, c.author, c.created_at, c.updated_at, c.version, c.deleted_at, c.score, c.reply_count, c.hidden_at, c.locked_at, c.status, c.moderation_reason
+			FROM comments c
+			JOIN tree t ON c.parent_id = t.id
+			WHERE c.status = 'PUBLISHED'
+		)
+		SELECT ` + commentColumns + ` FROM tree ORDER BY created_at, id`
+
+	return s.queryComments(ctx, query, id)
+}
+
+// commentExists возвращает ErrCommentNotFound, если комментария нет в БД
+func (s *PostgresStorage) commentExists(ctx context.Context, id string) error {
+	var exists bool
+	if err := s.conn(ctx).QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM comments WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrCommentNotFound
+	}
+	return nil
+}
+
+// GetReplies возвращает страницу прямых ответов на комментарий из БД в заданном порядке
+func (s *PostgresStorage) GetReplies(ctx context.Context, parentID string, order CommentOrder, page PageParams) (*CommentPage, error) {
+	var exists bool
+	if err := s.conn(ctx).QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM comments WHERE id = $1)`, parentID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrCommentNotFound
+	}
+
+	result := &CommentPage{}
+	countQuery := `SELECT COUNT(*) FROM comments WHERE parent_id = $1 AND ` + publishedFilter
+	if err := s.conn(ctx).QueryRowContext(ctx, countQuery, parentID).Scan(&result.TotalCount); err != nil {
+		return nil, err
+	}
+
+	query, args, err := s.buildPageQuery(ctx,
+		`SELECT `+commentColumns+` FROM comments`, "comments",
+		"parent_id = $1 AND "+publishedFilter, []interface{}{parentID}, order.column(), !order.Desc, page)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Comments, err = s.queryComments(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Comments, result.HasPreviousPage, result.HasNextPage = trimPage(result.Comments, page)
+	return result, nil
+}
+
+// rowScanner - общий интерфейс *sql.Row и *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPost читает пост из строки с колонками postColumns
+func scanPost(row rowScanner) (*models.Post, error) {
+	post := &models.Post{}
+	var allowComments bool
+	err := row.Scan(&post.ID, &post.Title, &post.Content, &allowComments,
+		&post.Author, &post.CreatedAt, &post.UpdatedAt, &post.Version)
+	if err != nil {
+		return nil, err
+	}
+	post.CommentsDisabled = !allowComments
+
+	post.Comments = []*models.Comment{}
+	return post, nil
+}
+
+// scanComment читает комментарий из строки с колонками commentColumns
+func scanComment(row rowScanner) (*models.Comment, error) {
 	comment := &models.Comment{}
 	var parentID sql.NullString
-	err := row.Scan(&comment.ID, &comment.PostID, &parentID, &comment.Content)
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("comment not found")
-	}
+	var deletedAt, hiddenAt, lockedAt sql.NullTime
+
+	err := row.Scan(&comment.ID, &comment.PostID, &parentID, &comment.Content,
+		&comment.Author, &comment.CreatedAt, &comment.UpdatedAt, &comment.Version, &deletedAt,
+		&comment.Score, &comment.ReplyCount, &hiddenAt, &lockedAt, &comment.Status, &comment.ModerationReason)
 	if err != nil {
 		return nil, err
 	}
@@ -136,57 +697,439 @@ func (s *PostgresStorage) GetComment(id string) (*models.Comment, error) {
 		parentIDStr := parentID.String
 		comment.ParentID = &parentIDStr
 	}
+	if deletedAt.Valid {
+		comment.IsDeleted = true
+		comment.DeletedAt = &deletedAt.Time
+	}
+	comment.IsHidden = hiddenAt.Valid
+	comment.IsLocked = lockedAt.Valid
 
 	comment.Replies = []*models.Comment{}
 	return comment, nil
 }
 
-// GetCommentsByPostID возвращает все комментарии для поста из БД
-func (s *PostgresStorage) GetCommentsByPostID(postID string) ([]*models.Comment, error) {
-	query := `SELECT id, post_id, parent_id, content FROM comments WHERE post_id = $1 ORDER BY created_at`
-	rows, err := s.db.Query(query, postID)
+// queryPosts выполняет запрос, возвращающий колонки postColumns, и собирает посты
+func (s *PostgresStorage) queryPosts(ctx context.Context, query string, args ...interface{}) ([]*models.Post, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var comments []*models.Comment
+	posts := []*models.Post{}
 	for rows.Next() {
-		comment := &models.Comment{}
-		var parentID sql.NullString
-
-		if err := rows.Scan(&comment.ID, &comment.PostID, &parentID, &comment.Content); err != nil {
+		post, err := scanPost(rows)
+		if err != nil {
 			return nil, err
 		}
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-		if parentID.Valid {
-			parentIDStr := parentID.String
-			comment.ParentID = &parentIDStr
-		}
+	return posts, nil
+}
 
-		comment.Replies = []*models.Comment{}
+// queryComments выполняет запрос, возвращающий колонки commentColumns, и собирает комментарии
+func (s *PostgresStorage) queryComments(ctx context.Context, query string, args ...interface{}) ([]*models.Comment, error) {
+	rows, err := s.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []*models.Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
 		comments = append(comments, comment)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
 	return comments, nil
 }
 
-// DeleteComment удаляет комментарий по ID из БД
-func (s *PostgresStorage) DeleteComment(id string) error {
-	query := `DELETE FROM comments WHERE id = $1`
-	result, err := s.db.Exec(query, id)
+// buildPageQuery дописывает к запросу условия по курсорам, сортировку и LIMIT.
+// Порядок стабильный: (column, created_at, id), column может быть пустым,
+// asc задает естественное направление.
+// При пагинации назад (Last) строки выбираются в обратном порядке,
+// trimPage потом разворачивает их обратно.
+func (s *PostgresStorage) buildPageQuery(
+	ctx context.Context, base, table, filter string, args []interface{}, column string, asc bool, page PageParams,
+) (string, []interface{}, error) {
+	keys := []string{"created_at", "id"}
+	if column != "" {
+		keys = append([]string{column}, keys...)
+	}
+	keyList := strings.Join(keys, ", ")
+
+	afterOp, beforeOp := "<", ">"
+	forward, backward := "DESC", "ASC"
+	if asc {
+		afterOp, beforeOp = ">", "<"
+		forward, backward = "ASC", "DESC"
+	}
+
+	var conditions []string
+	if filter != "" {
+		conditions = append(conditions, filter)
+	}
+
+	// Условие вида (created_at, id) > (created_at, id) курсора
+	addCursor := func(id string, op string) error {
+		var exists bool
+		existsQuery := `SELECT EXISTS(SELECT 1 FROM ` + table + ` WHERE id = $1)`
+		if err := s.conn(ctx).QueryRowContext(ctx, existsQuery, id).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return errCursorNotFound
+		}
+		args = append(args, id)
+		conditions = append(conditions, fmt.Sprintf(
+			"(%s) %s (SELECT %s FROM %s WHERE id = $%d)", keyList, op, keyList, table, len(args)))
+		return nil
+	}
+	if page.After != nil {
+		if err := addCursor(*page.After, afterOp); err != nil {
+			return "", nil, err
+		}
+	}
+	if page.Before != nil {
+		if err := addCursor(*page.Before, beforeOp); err != nil {
+			return "", nil, err
+		}
+	}
+
+	query := base
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// Берем на одну строку больше, чтобы узнать, есть ли следующая страница
+	orderBy := func(direction string) string {
+		parts := make([]string, len(keys))
+		for i, key := range keys {
+			parts[i] = key + " " + direction
+		}
+		return " ORDER BY " + strings.Join(parts, ", ")
+	}
+	switch {
+	case page.First != nil:
+		query += orderBy(forward) + fmt.Sprintf(" LIMIT %d", *page.First+1)
+	case page.Last != nil:
+		query += orderBy(backward) + fmt.Sprintf(" LIMIT %d", *page.Last+1)
+	default:
+		query += orderBy(forward)
+	}
+
+	return query, args, nil
+}
+
+// trimPage отрезает лишнюю строку, полученную из buildPageQuery,
+// и вычисляет признаки наличия соседних страниц
+func trimPage[T any](items []T, page PageParams) ([]T, bool, bool) {
+	hasPrev := page.After != nil
+	hasNext := page.Before != nil
+
+	switch {
+	case page.First != nil:
+		if len(items) > *page.First {
+			items = items[:*page.First]
+			hasNext = true
+		}
+	case page.Last != nil:
+		if len(items) > *page.Last {
+			items = items[:*page.Last]
+			hasPrev = true
+		}
+		// Разворачиваем обратно в естественный порядок
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	return items, hasPrev, hasNext
+}
+
+// VoteComment сохраняет голос пользователя и пересчитывает рейтинг комментария в одной транзакции
+func (s *PostgresStorage) VoteComment(ctx context.Context, commentID, userID string, value int) (*models.Comment, error) {
+	return s.updateReaction(ctx, commentID, userID,
+		`INSERT INTO comment_reactions (comment_id, user_id, value) VALUES ($1, $2, $3)
+		ON CONFLICT (comment_id, user_id) DO UPDATE SET value = EXCLUDED.value, updated_at = now()`, value)
+}
+
+// ReactToComment сохраняет эмодзи-реакцию пользователя (nil снимает реакцию)
+func (s *PostgresStorage) ReactToComment(ctx context.Context, commentID, userID string, emoji *string) (*models.Comment, error) {
+	return s.updateReaction(ctx, commentID, userID,
+		`INSERT INTO comment_reactions (comment_id, user_id, emoji) VALUES ($1, $2, $3)
+		ON CONFLICT (comment_id, user_id) DO UPDATE SET emoji = EXCLUDED.emoji, updated_at = now()`, emoji)
+}
+
+// updateReaction выполняет upsert записи реакции и пересчитывает рейтинг.
+// Строка комментария блокируется, чтобы параллельные голоса не потеряли обновление рейтинга
+func (s *PostgresStorage) updateReaction(ctx context.Context, commentID, userID, upsert string, arg interface{}) (*models.Comment, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer tx.Rollback()
 
+	var status models.CommentStatus
+	var deletedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, `SELECT status, deleted_at FROM comments WHERE id = $1 FOR UPDATE`, commentID).Scan(&status, &deletedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deletedAt.Valid {
+		return nil, ErrCommentDeleted
+	}
+	// Комментарии на проверке и отклоненные клиентам не видны
+	if status != models.CommentPublished {
+		return nil, ErrCommentNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, upsert, commentID, userID, arg); err != nil {
+		return nil, err
+	}
+	// Пустая запись не нужна: ее отсутствие означает то же самое
+	emptyQuery := `DELETE FROM comment_reactions WHERE comment_id = $1 AND user_id = $2 AND value = 0 AND emoji IS NULL`
+	if _, err := tx.ExecContext(ctx, emptyQuery, commentID, userID); err != nil {
+		return nil, err
+	}
+
+	scoreQuery := `UPDATE comments
+		SET score = (SELECT COALESCE(SUM(value), 0) FROM comment_reactions WHERE comment_id = $1)
+		WHERE id = $1 RETURNING ` + commentColumns
+	comment, err := scanComment(tx.QueryRowContext(ctx, scoreQuery, commentID))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// GetReaction возвращает голос и реакцию пользователя из БД
+func (s *PostgresStorage) GetReaction(ctx context.Context, commentID, userID string) (*models.Reaction, error) {
+	reaction := &models.Reaction{CommentID: commentID, UserID: userID}
+	var emoji sql.NullString
+	query := `SELECT value, emoji, updated_at FROM comment_reactions WHERE comment_id = $1 AND user_id = $2`
+	err := s.conn(ctx).QueryRowContext(ctx, query, commentID, userID).Scan(&reaction.Value, &emoji, &reaction.UpdatedAt)
+	if err == sql.ErrNoRows {
+		// Записи нет - либо пользователь не реагировал, либо нет самого комментария
+		if _, err := s.GetComment(ctx, commentID); err != nil {
+			return nil, err
+		}
+		return reaction, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if emoji.Valid {
+		reaction.Emoji = &emoji.String
+	}
+	return reaction, nil
+}
+
+// GetReactionCounts возвращает число реакций каждым эмодзи из БД
+func (s *PostgresStorage) GetReactionCounts(ctx context.Context, commentID string) ([]*models.ReactionCount, error) {
+	if _, err := s.GetComment(ctx, commentID); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT emoji, COUNT(*) FROM comment_reactions
+		WHERE comment_id = $1 AND emoji IS NOT NULL
+		GROUP BY emoji ORDER BY COUNT(*) DESC, emoji COLLATE "C"`
+	rows, err := s.conn(ctx).QueryContext(ctx, query, commentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []*models.ReactionCount{}
+	for rows.Next() {
+		count := &models.ReactionCount{}
+		if err := rows.Scan(&count.Emoji, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+// GetReactionCountsByCommentIDs возвращает число реакций нескольких комментариев одним запросом
+func (s *PostgresStorage) GetReactionCountsByCommentIDs(ctx context.Context, commentIDs []string) (map[string][]*models.ReactionCount, error) {
+	query := `SELECT comment_id, emoji, COUNT(*) FROM comment_reactions
+		WHERE comment_id = ANY($1) AND emoji IS NOT NULL
+		GROUP BY comment_id, emoji ORDER BY comment_id, COUNT(*) DESC, emoji COLLATE "C"`
+	rows, err := s.conn(ctx).QueryContext(ctx, query, pq.Array(commentIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string][]*models.ReactionCount, len(commentIDs))
+	for rows.Next() {
+		var commentID string
+		count := &models.ReactionCount{}
+		if err := rows.Scan(&commentID, &count.Emoji, &count.Count); err != nil {
+			return nil, err
+		}
+		result[commentID] = append(result[commentID], count)
+	}
+	return result, rows.Err()
+}
+
+// GetReactionsByCommentIDs возвращает голоса и реакции пользователя на несколько комментариев одним запросом
+func (s *PostgresStorage) GetReactionsByCommentIDs(ctx context.Context, commentIDs []string, userID string) (map[string]*models.Reaction, error) {
+	query := `SELECT comment_id, value, emoji, updated_at FROM comment_reactions
+		WHERE comment_id = ANY($1) AND user_id = $2`
+	rows, err := s.conn(ctx).QueryContext(ctx, query, pq.Array(commentIDs), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string]*models.Reaction)
+	for rows.Next() {
+		reaction := &models.Reaction{UserID: userID}
+		var emoji sql.NullString
+		if err := rows.Scan(&reaction.CommentID, &reaction.Value, &emoji, &reaction.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if emoji.Valid {
+			reaction.Emoji = &emoji.String
+		}
+		result[reaction.CommentID] = reaction
+	}
+	return result, rows.Err()
+}
+
+// HideComment скрывает комментарий или снимает скрытие
+func (s *PostgresStorage) HideComment(ctx context.Context, id string, hidden bool) (*models.Comment, error) {
+	query := `UPDATE comments SET hidden_at = CASE WHEN $2 THEN COALESCE(hidden_at, now()) END
+		WHERE id = $1 RETURNING ` + commentColumns
+	return s.updateCommentRow(ctx, query, id, hidden)
+}
+
+// LockThread закрывает ветку комментария для новых ответов или открывает ее
+func (s *PostgresStorage) LockThread(ctx context.Context, id string, locked bool) (*models.Comment, error) {
+	query := `UPDATE comments SET locked_at = CASE WHEN $2 THEN COALESCE(locked_at, now()) END
+		WHERE id = $1 RETURNING ` + commentColumns
+	return s.updateCommentRow(ctx, query, id, locked)
+}
+
+// updateCommentRow выполняет UPDATE ... RETURNING одного комментария
+func (s *PostgresStorage) updateCommentRow(ctx context.Context, query string, args ...interface{}) (*models.Comment, error) {
+	comment, err := scanComment(s.conn(ctx).QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// BanUserFromPost запрещает пользователю комментировать пост
+func (s *PostgresStorage) BanUserFromPost(ctx context.Context, ban *models.PostBan) error {
+	query := `INSERT INTO post_bans (post_id, user_id, reason, banned_by) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (post_id, user_id) DO UPDATE SET reason = EXCLUDED.reason, banned_by = EXCLUDED.banned_by, created_at = now()
+		RETURNING created_at`
+	err := s.conn(ctx).QueryRowContext(ctx, query, ban.PostID, ban.UserID, ban.Reason, ban.BannedBy).Scan(&ban.CreatedAt)
+	if err != nil {
+		return mapPQError(err)
+	}
+	return nil
+}
+
+// UnbanUserFromPost снимает запрет комментировать пост
+func (s *PostgresStorage) UnbanUserFromPost(ctx context.Context, postID, userID string) error {
+	result, err := s.conn(ctx).ExecContext(ctx, `DELETE FROM post_bans WHERE post_id = $1 AND user_id = $2`, postID, userID)
+	if err != nil {
+		return err
+	}
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
-
 	if rowsAffected == 0 {
-		return fmt.Errorf("comment not found")
+		return ErrNotFound
 	}
-
 	return nil
 }
+
+// AddAuditEntry добавляет запись в журнал модерации
+func (s *PostgresStorage) AddAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	query := `INSERT INTO audit_log (actor_id, action, target_id, details) VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+	return s.conn(ctx).QueryRowContext(ctx, query, entry.ActorID, entry.Action, entry.TargetID, entry.Details).
+		Scan(&entry.ID, &entry.CreatedAt)
+}
+
+// GetAuditLog возвращает последние записи журнала модерации
+func (s *PostgresStorage) GetAuditLog(ctx context.Context, limit int) ([]*models.AuditEntry, error) {
+	query := `SELECT id, actor_id, action, target_id, details, created_at FROM audit_log
+		ORDER BY created_at DESC, id DESC LIMIT $1`
+	rows, err := s.conn(ctx).QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*models.AuditEntry{}
+	for rows.Next() {
+		entry := &models.AuditEntry{}
+		if err := rows.Scan(&entry.ID, &entry.ActorID, &entry.Action, &entry.TargetID, &entry.Details, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// GetModerationQueue возвращает комментарии, ожидающие проверки, в порядке создания
+func (s *PostgresStorage) GetModerationQueue(ctx context.Context, postID string, limit int) ([]*models.Comment, error) {
+	query := `SELECT ` + commentColumns + ` FROM comments
+		WHERE status = 'PENDING' AND ($1 = '' OR post_id = $1)
+		ORDER BY created_at, id LIMIT $2`
+	return s.queryComments(ctx, query, postID, limit)
+}
+
+// SetCommentStatus переводит комментарий из очереди модерации в статус status.
+// Счетчик ответов родителя обновляет триггер
+func (s *PostgresStorage) SetCommentStatus(ctx context.Context, id string, status models.CommentStatus) (*models.Comment, error) {
+	query := `UPDATE comments SET status = $2 WHERE id = $1 AND status = 'PENDING' RETURNING ` + commentColumns
+	comment, err := scanComment(s.conn(ctx).QueryRowContext(ctx, query, id, string(status)))
+	if err == sql.ErrNoRows {
+		// Ничего не обновили - либо комментария нет, либо он уже проверен
+		var exists bool
+		if err := s.conn(ctx).QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM comments WHERE id = $1)`, id).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrCommentNotFound
+		}
+		return nil, ErrNotPending
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+var _ Storage = (*PostgresStorage)(nil)
+var _ idgen.Generator = (*PostgresStorage)(nil)
@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunTombstonePurge периодически окончательно удаляет мягко удаленные комментарии
+// старше retention, на которые не осталось ответов. Блокируется до отмены ctx
+func RunTombstonePurge(ctx context.Context, store Storage, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := store.PurgeDeletedComments(ctx, time.Now().Add(-retention))
+			if err != nil {
+				log.Printf("Ошибка очистки удаленных комментариев: %v", err)
+				continue
+			}
+			if purged > 0 {
+				log.Printf("Окончательно удалено комментариев: %d", purged)
+			}
+		}
+	}
+}
@@ -1,19 +1,105 @@
 package storage
 
-import "graphql-comments/internal/models"
+import (
+	"context"
+	"time"
+
+	"graphql-comments/internal/models"
+)
 
 // Storage - определяет все методы,
 // поддерживает оба хранилища (in-memory или postgres)
 type Storage interface {
 	// Методы для работы с постами
-	CreatePost(post *models.Post) error
-	GetPost(id string) (*models.Post, error)
-	GetAllPosts() ([]*models.Post, error)
-	DeletePost(id string) error
+	CreatePost(ctx context.Context, post *models.Post) error
+	GetPost(ctx context.Context, id string) (*models.Post, error)
+	GetAllPosts(ctx context.Context) ([]*models.Post, error)
+	DeletePost(ctx context.Context, id string) error
+	// UpdatePost обновляет заголовок и/или текст поста, если версия совпадает
+	UpdatePost(ctx context.Context, input *models.UpdatePostInput) (*models.Post, error)
+	// SetAllowComments включает или отключает комментарии к посту
+	SetAllowComments(ctx context.Context, postID string, allow bool) (*models.Post, error)
+	// GetPostsPage возвращает страницу постов (новые первыми)
+	GetPostsPage(ctx context.Context, page PageParams) (*PostPage, error)
 
 	// Методы для работы с комментариями
-	CreateComment(comment *models.Comment) error
-	GetComment(id string) (*models.Comment, error)
-	GetCommentsByPostID(postID string) ([]*models.Comment, error)
-	DeleteComment(id string) error
+	CreateComment(ctx context.Context, comment *models.Comment) error
+	GetComment(ctx context.Context, id string) (*models.Comment, error)
+	GetCommentsByPostID(ctx context.Context, postID string) ([]*models.Comment, error)
+	// GetCommentsByPostIDs возвращает комментарии сразу нескольких постов одним запросом
+	// (ключ - ID поста, порядок комментариев - по времени создания)
+	GetCommentsByPostIDs(ctx context.Context, postIDs []string) (map[string][]*models.Comment, error)
+	DeleteComment(ctx context.Context, id string) error
+	// SoftDeleteComment помечает комментарий удаленным, оставляя его в дереве
+	// (ответы на него остаются видимыми)
+	SoftDeleteComment(ctx context.Context, id string) (*models.Comment, error)
+	// RestoreComment снимает с комментария пометку об удалении
+	RestoreComment(ctx context.Context, id string) (*models.Comment, error)
+	// PurgeDeletedComments окончательно удаляет комментарии, помеченные удаленными
+	// раньше before, если на них не осталось ответов. Возвращает число удаленных
+	PurgeDeletedComments(ctx context.Context, before time.Time) (int, error)
+	// UpdateComment обновляет текст комментария, если версия совпадает,
+	// и сохраняет предыдущий текст в истории правок. Отмеченную фильтрами правку
+	// опубликованного комментария не сохраняет и возвращает ErrEditFlagged
+	UpdateComment(ctx context.Context, input *models.UpdateCommentInput) (*models.Comment, error)
+	// GetCommentRevisions возвращает историю правок комментария (от старых к новым)
+	GetCommentRevisions(ctx context.Context, commentID string) ([]*models.CommentRevision, error)
+	// GetCommentsPage возвращает страницу комментариев поста (в порядке создания)
+	GetCommentsPage(ctx context.Context, postID string, page PageParams) (*CommentPage, error)
+	// GetCommentsUpToDepth возвращает комментарии поста не глубже maxDepth уровней
+	// (корневые комментарии и комментарии, чьего родителя нет в посте, - первый уровень)
+	GetCommentsUpToDepth(ctx context.Context, postID string, maxDepth int) ([]*models.Comment, error)
+	// GetCommentAncestors возвращает опубликованных предков комментария в том же посте от корня
+	// к родителю, не больше limit ближайших (limit < 0 - вся цепочка). Цепочка обрывается на
+	// первом неопубликованном предке
+	GetCommentAncestors(ctx context.Context, id string, limit int) ([]*models.Comment, error)
+	// GetCommentSubtree возвращает опубликованные ответы на комментарий на любой глубине
+	// (без самого комментария) плоским списком в порядке создания
+	GetCommentSubtree(ctx context.Context, id string) ([]*models.Comment, error)
+	// GetReplies возвращает страницу прямых ответов на комментарий в заданном порядке
+	GetReplies(ctx context.Context, parentID string, order CommentOrder, page PageParams) (*CommentPage, error)
+
+	// Методы для работы с голосами и реакциями (у пользователя одна запись на комментарий)
+	// VoteComment ставит голос value (-1, 0 или 1; 0 снимает голос) и пересчитывает рейтинг
+	VoteComment(ctx context.Context, commentID, userID string, value int) (*models.Comment, error)
+	// ReactToComment ставит эмодзи-реакцию пользователя, nil снимает ее
+	// Оба метода принимают только опубликованные комментарии: на неопубликованные - ErrCommentNotFound
+	ReactToComment(ctx context.Context, commentID, userID string, emoji *string) (*models.Comment, error)
+	// GetReaction возвращает голос и реакцию пользователя
+	// (пустую запись, если пользователь еще не реагировал)
+	GetReaction(ctx context.Context, commentID, userID string) (*models.Reaction, error)
+	// GetReactionCounts возвращает число реакций каждым эмодзи (самые частые первыми)
+	GetReactionCounts(ctx context.Context, commentID string) ([]*models.ReactionCount, error)
+	// GetReactionCountsByCommentIDs возвращает число реакций сразу нескольких комментариев одним запросом
+	// (ключ - ID комментария; комментариев без реакций в ответе нет)
+	GetReactionCountsByCommentIDs(ctx context.Context, commentIDs []string) (map[string][]*models.ReactionCount, error)
+	// GetReactionsByCommentIDs возвращает голоса и реакции пользователя на несколько комментариев
+	// (ключ - ID комментария; комментариев, на которые он не реагировал, в ответе нет)
+	GetReactionsByCommentIDs(ctx context.Context, commentIDs []string, userID string) (map[string]*models.Reaction, error)
+
+	// Методы модерации
+	// HideComment скрывает комментарий (hidden = false - показывает снова)
+	HideComment(ctx context.Context, id string, hidden bool) (*models.Comment, error)
+	// LockThread закрывает ветку комментария для новых ответов на любой глубине
+	LockThread(ctx context.Context, id string, locked bool) (*models.Comment, error)
+	// BanUserFromPost запрещает пользователю комментировать пост (повторный бан обновляет причину)
+	BanUserFromPost(ctx context.Context, ban *models.PostBan) error
+	// UnbanUserFromPost снимает запрет; ErrNotFound, если запрета не было
+	UnbanUserFromPost(ctx context.Context, postID, userID string) error
+	// WithAudit выполняет действие модератора fn и записывает entry в журнал атомарно:
+	// если запись не удалась, действие откатывается. fn работает с хранилищем через свой ctx
+	WithAudit(ctx context.Context, entry *models.AuditEntry, fn func(ctx context.Context) error) error
+	// AddAuditEntry записывает действие модератора, ID и время проставляет хранилище
+	AddAuditEntry(ctx context.Context, entry *models.AuditEntry) error
+	// GetAuditLog возвращает последние limit записей журнала (новые первыми)
+	GetAuditLog(ctx context.Context, limit int) ([]*models.AuditEntry, error)
+
+	// Методы премодерации. Комментарии в статусе, отличном от PUBLISHED, не попадают
+	// в списки и деревья комментариев, на них нельзя отвечать; GetComment возвращает их
+	// GetModerationQueue возвращает до limit комментариев, ожидающих проверки
+	// (старые первыми; postID пустой - по всем постам)
+	GetModerationQueue(ctx context.Context, postID string, limit int) ([]*models.Comment, error)
+	// SetCommentStatus публикует или отклоняет комментарий из очереди;
+	// ErrNotPending, если комментарий не ожидает проверки
+	SetCommentStatus(ctx context.Context, id string, status models.CommentStatus) (*models.Comment, error)
 }
@@ -0,0 +1,281 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"graphql-comments/internal/models"
+)
+
+// Timeouts - ограничения времени на операции хранилища (0 - без ограничения)
+type Timeouts struct {
+	// Read - для операций чтения
+	Read time.Duration
+	// Write - для операций, изменяющих данные
+	Write time.Duration
+}
+
+// timeoutStorage ограничивает время выполнения каждой операции хранилища.
+// Операция, не уложившаяся в таймаут, отменяется через контекст
+// и возвращает ошибку, совместимую с context.DeadlineExceeded
+type timeoutStorage struct {
+	next     Storage
+	timeouts Timeouts
+}
+
+// WithTimeouts оборачивает хранилище таймаутами на операции
+func WithTimeouts(store Storage, timeouts Timeouts) Storage {
+	if timeouts == (Timeouts{}) {
+		return store
+	}
+	return &timeoutStorage{next: store, timeouts: timeouts}
+}
+
+// withTimeout выполняет операцию с таймаутом
+func withTimeout[T any](ctx context.Context, timeout time.Duration, op func(context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		return op(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result, err := op(ctx)
+	// Драйвер может вернуть свою ошибку отмены запроса - приводим ее к DeadlineExceeded
+	if err != nil && ctx.Err() == context.DeadlineExceeded && !errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("%w: %v", context.DeadlineExceeded, err)
+	}
+	return result, err
+}
+
+// execWithTimeout выполняет операцию без результата с таймаутом
+func execWithTimeout(ctx context.Context, timeout time.Duration, op func(context.Context) error) error {
+	_, err := withTimeout(ctx, timeout, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, op(ctx)
+	})
+	return err
+}
+
+func (s *timeoutStorage) CreatePost(ctx context.Context, post *models.Post) error {
+	return execWithTimeout(ctx, s.timeouts.Write, func(ctx context.Context) error {
+		return s.next.CreatePost(ctx, post)
+	})
+}
+
+func (s *timeoutStorage) GetPost(ctx context.Context, id string) (*models.Post, error) {
+	return withTimeout(ctx, s.timeouts.Read, func(ctx context.Context) (*models.Post, error) {
+		return s.next.GetPost(ctx, id)
+	})
+}
+
+func (s *timeoutStorage) GetAllPosts(ctx context.Context) ([]*models.Post, error) {
+	return withTimeout(ctx, s.timeouts.Read, s.next.GetAllPosts)
+}
+
+func (s *timeoutStorage) DeletePost(ctx context.Context, id string) error {
+	return execWithTimeout(ctx, s.timeouts.Write, func(ctx context.Context) error {
+		return s.next.DeletePost(ctx, id)
+	})
+}
+
+func (s *timeoutStorage) UpdatePost(ctx context.Context, input *models.UpdatePostInput) (*models.Post, error) {
+	return withTimeout(ctx, s.timeouts.Write, func(ctx context.Context) (*models.Post, error) {
+		return s.next.UpdatePost(ctx, input)
+	})
+}
+
+func (s *timeoutStorage) SetAllowComments(ctx context.Context, postID string, allow bool) (*models.Post, error) {
+	return withTimeout(ctx, s.timeouts.Write, func(ctx context.Context) (*models.Post, error) {
+		return s.next.SetAllowComments(ctx, postID, allow)
+	})
+}
+
+func (s *timeoutStorage) GetPostsPage(ctx context.Context, page PageParams) (*PostPage, error) {
+	return withTimeout(ctx, s.timeouts.Read, func(ctx context.Context) (*PostPage, error) {
+		return s.next.GetPostsPage(ctx, page)
+	})
+}
+
+func (s *timeoutStorage) CreateComment(ctx context.Context, comment *models.Comment) error {
+	return execWithTimeout(ctx, s.timeouts.Write, func(ctx context.Context) error {
+		return s.next.CreateComment(ctx, comment)
+	})
+}
+
+func (s *timeoutStorage) GetComment(ctx context.Context, id string) (*models.Comment, error) {
+	return withTimeout(ctx, s.timeouts.Read, func(ctx context.Context) (*models.Comment, error) {
+		return s.next.GetComment(ctx, id)
+	})
+}
+
+func (s *timeoutStorage) GetCommentsByPostID(ctx context.Context, postID string) ([]*models.Comment, error) {
+	return withTimeout(ctx, s.timeouts.Read, func(ctx context.Context) ([]*models.Comment, error) {
+		return s.next.GetCommentsByPostID(ctx, postID)
+	})
+}
+
+func (s *timeoutStorage) GetCommentsByPostIDs(ctx context.Context, postIDs []string) (map[string][]*models.Comment, error) {
+	return withTimeout(ctx, s.timeouts.Read, func(ctx context.Context) (map[string][]*models.Comment, error) {
+		return s.next.GetCommentsByPostIDs(ctx, postIDs)
+	})
+}
+
+func (s *timeoutStorage) DeleteComment(ctx context.Context, id string) error {
+	return execWithTimeout(ctx, s.timeouts.Write, func(ctx context.Context) error {
+		return s.next.DeleteComment(ctx, id)
+	})
+}
+
+func (s *timeoutStorage) SoftDeleteComment(ctx context.Context, id string) (*models.Comment, error) {
+	return withTimeout(ctx, s.timeouts.Write, func(ctx context.Context) (*models.Comment, error) {
+		return s.next.SoftDeleteComment(ctx, id)
+	})
+}
+
+func (s *timeoutStorage) RestoreComment(ctx context.Context, id string) (*models.Comment, error) {
+	return withTimeout(ctx, s.timeouts.Write, func(ctx context.Context) (*models.Comment, error) {
+		return s.next.RestoreComment(ctx, id)
+	})
+}
+
+func (s *timeoutStorage) PurgeDeletedComments(ctx context.Context, before time.Time) (int, error) {
+	return withTimeout(ctx, s.timeouts.Write, func(ctx context.Context) (int, error) {
+		return s.next.PurgeDeletedComments(ctx, before)
+	})
+}
+
+func (s *timeoutStorage) UpdateComment(ctx context.Context, input *models.UpdateCommentInput) (*models.Comment, error) {
+	return withTimeout(ctx, s.timeouts.Write, func(ctx context.Context) (*models.Comment, error) {
+		return s.next.UpdateComment(ctx, input)
+	})
+}
+
+func (s *timeoutStorage) GetCommentRevisions(ctx context.Context, commentID string) ([]*models.CommentRevision, error) {
+	return withTimeout(ctx, s.timeouts.Read, func(ctx context.Context) ([]*models.CommentRevision, error) {
+		return s.next.GetCommentRevisions(ctx, commentID)
+	})
+}
+
+func (s *timeoutStorage) GetCommentsPage(ctx context.Context, postID string, page PageParams) (*CommentPage, error) {
+	return withTimeout(ctx, s.timeouts.Read, func(ctx context.Context) (*CommentPage, error) {
+		return s.next.GetCommentsPage(ctx, postID, page)
+	})
+}
+
+func (s *timeoutStorage) GetCommentsUpToDepth(ctx context.Context, postID string, maxDepth int) ([]*models.Comment, error) {
+	return withTimeout(ctx, s.timeouts.Read, func(ctx context.Context) ([]*models.Comment, error) {
+		return s.next.GetCommentsUpToDepth(ctx, postID, maxDepth)
+	})
+}
+
+func (s *timeoutStorage) GetCommentAncestors(ctx context.Context, id string, limit int) ([]*models.Comment, error) {
+	return withTimeout(ctx, s.timeouts.Read, func(ctx context.Context) ([]*models.Comment, error) {
+		return s.next.GetCommentAncestors(ctx, id, limit)
+	})
+}
+
+func (s *timeoutStorage) GetCommentSubtree(ctx context.Context, id string) ([]*models.Comment, error) {
+	return withTimeout(ctx, s.timeouts.Read, func(ctx context.Context) ([]*models.Comment, error) {
+		return s.next.GetCommentSubtree(ctx, id)
+	})
+}
+
+func (s *timeoutStorage) GetReplies(ctx context.Context, parentID string, order CommentOrder, page PageParams) (*CommentPage, error) {
+	return withTimeout(ctx, s.timeouts.Read, func(ctx context.Context) (*CommentPage, error) {
+		return s.next.GetReplies(ctx, parentID, order, page)
+	})
+}
+
+func (s *timeoutStorage) VoteComment(ctx context.Context, commentID, userID string, value int) (*models.Comment, error) {
+	return withTimeout(ctx, s.timeouts.Write, func(ctx context.Context) (*models.Comment, error) {
+		return s.next.VoteComment(ctx, commentID, userID, value)
+	})
+}
+
+func (s *timeoutStorage) ReactToComment(ctx context.Context, commentID, userID string, emoji *string) (*models.Comment, error) {
+	return withTimeout(ctx, s.timeouts.Write, func(ctx context.Context) (*models.Comment, error) {
+		return s.next.ReactToComment(ctx, commentID, userID, emoji)
+	})
+}
+
+func (s *timeoutStorage) GetReaction(ctx context.Context, commentID, userID string) (*models.Reaction, error) {
+	return withTimeout(ctx, s.timeouts.Read, func(ctx context.Context) (*models.Reaction, error) {
+		return s.next.GetReaction(ctx, commentID, userID)
+	})
+}
+
+func (s *timeoutStorage) GetReactionCountsByCommentIDs(ctx context.Context, commentIDs []string) (map[string][]*models.ReactionCount, error) {
+	return withTimeout(ctx, s.timeouts.Read, func(ctx context.Context) (map[string][]*models.ReactionCount, error) {
+		return s.next.GetReactionCountsByCommentIDs(ctx, commentIDs)
+	})
+}
+
+func (s *timeoutStorage) GetReactionsByCommentIDs(ctx context.Context, commentIDs []string, userID string) (map[string]*models.Reaction, error) {
+	return withTimeout(ctx, s.timeouts.Read, func(ctx context.Context) (map[string]*models.Reaction, error) {
+		return s.next.GetReactionsByCommentIDs(ctx, commentIDs, userID)
+	})
+}
+
+func (s *timeoutStorage) GetReactionCounts(ctx context.Context, commentID string) ([]*models.ReactionCount, error) {
+	return withTimeout(ctx, s.timeouts.Read, func(ctx context.Context) ([]*models.ReactionCount, error) {
+		return s.next.GetReactionCounts(ctx, commentID)
+	})
+}
+
+func (s *timeoutStorage) HideComment(ctx context.Context, id string, hidden bool) (*models.Comment, error) {
+	return withTimeout(ctx, s.timeouts.Write, func(ctx context.Context) (*models.Comment, error) {
+		return s.next.HideComment(ctx, id, hidden)
+	})
+}
+
+func (s *timeoutStorage) LockThread(ctx context.Context, id string, locked bool) (*models.Comment, error) {
+	return withTimeout(ctx, s.timeouts.Write, func(ctx context.Context) (*models.Comment, error) {
+		return s.next.LockThread(ctx, id, locked)
+	})
+}
+
+func (s *timeoutStorage) BanUserFromPost(ctx context.Context, ban *models.PostBan) error {
+	return execWithTimeout(ctx, s.timeouts.Write, func(ctx context.Context) error {
+		return s.next.BanUserFromPost(ctx, ban)
+	})
+}
+
+func (s *timeoutStorage) UnbanUserFromPost(ctx context.Context, postID, userID string) error {
+	return execWithTimeout(ctx, s.timeouts.Write, func(ctx context.Context) error {
+		return s.next.UnbanUserFromPost(ctx, postID, userID)
+	})
+}
+
+func (s *timeoutStorage) WithAudit(ctx context.Context, entry *models.AuditEntry, fn func(ctx context.Context) error) error {
+	return execWithTimeout(ctx, s.timeouts.Write, func(ctx context.Context) error {
+		return s.next.WithAudit(ctx, entry, fn)
+	})
+}
+
+func (s *timeoutStorage) AddAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	return execWithTimeout(ctx, s.timeouts.Write, func(ctx context.Context) error {
+		return s.next.AddAuditEntry(ctx, entry)
+	})
+}
+
+func (s *timeoutStorage) GetAuditLog(ctx context.Context, limit int) ([]*models.AuditEntry, error) {
+	return withTimeout(ctx, s.timeouts.Read, func(ctx context.Context) ([]*models.AuditEntry, error) {
+		return s.next.GetAuditLog(ctx, limit)
+	})
+}
+
+func (s *timeoutStorage) GetModerationQueue(ctx context.Context, postID string, limit int) ([]*models.Comment, error) {
+	return withTimeout(ctx, s.timeouts.Read, func(ctx context.Context) ([]*models.Comment, error) {
+		return s.next.GetModerationQueue(ctx, postID, limit)
+	})
+}
+
+func (s *timeoutStorage) SetCommentStatus(ctx context.Context, id string, status models.CommentStatus) (*models.Comment, error) {
+	return withTimeout(ctx, s.timeouts.Write, func(ctx context.Context) (*models.Comment, error) {
+		return s.next.SetCommentStatus(ctx, id, status)
+	})
+}
+
+var _ Storage = (*timeoutStorage)(nil)
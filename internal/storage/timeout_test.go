@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"graphql-comments/internal/models"
+	"testing"
+	"time"
+)
+
+// blockingStorage ждет отмены контекста вместо чтения постов
+type blockingStorage struct {
+	Storage
+}
+
+func (s *blockingStorage) GetAllPosts(ctx context.Context) ([]*models.Post, error) {
+	<-ctx.Done()
+	return nil, errors.New("pq: canceling statement due to user request")
+}
+
+func TestWithTimeouts_DeadlineExceeded(t *testing.T) {
+	store := WithTimeouts(&blockingStorage{Storage: NewMemoryStorage()}, Timeouts{Read: 10 * time.Millisecond})
+
+	_, err := store.GetAllPosts(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Ожидали context.DeadlineExceeded, получили %v", err)
+	}
+}
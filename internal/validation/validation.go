@@ -0,0 +1,201 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CodeValidationFailed - код ошибки в extensions.code GraphQL ответа
+const CodeValidationFailed = "VALIDATION_FAILED"
+
+// Limits - ограничения на содержимое постов и комментариев.
+// Длины считаются в символах (рунах), а не в байтах
+type Limits struct {
+	MaxCommentLength     int
+	MaxPostTitleLength   int
+	MaxPostContentLength int
+	MaxAuthorLength      int
+}
+
+// DefaultLimits возвращает продуктовые ограничения по умолчанию
+func DefaultLimits() Limits {
+	return Limits{
+		MaxCommentLength:     2000,
+		MaxPostTitleLength:   200,
+		MaxPostContentLength: 50000,
+		MaxAuthorLength:      100,
+	}
+}
+
+// WithDefaults возвращает копию ограничений, в которой незаданные (нулевые) поля
+// заменены значениями из DefaultLimits
+func (l Limits) WithDefaults() Limits {
+	defaults := DefaultLimits()
+	if l.MaxCommentLength <= 0 {
+		l.MaxCommentLength = defaults.MaxCommentLength
+	}
+	if l.MaxPostTitleLength <= 0 {
+		l.MaxPostTitleLength = defaults.MaxPostTitleLength
+	}
+	if l.MaxPostContentLength <= 0 {
+		l.MaxPostContentLength = defaults.MaxPostContentLength
+	}
+	if l.MaxAuthorLength <= 0 {
+		l.MaxAuthorLength = defaults.MaxAuthorLength
+	}
+	return l
+}
+
+// FieldError - ошибка валидации одного поля
+type FieldError struct {
+	// Path - путь к полю в аргументах мутации, например ["input", "content"]
+	Path    []string `json:"path"`
+	Message string   `json:"message"`
+}
+
+// Error - ошибка валидации со списком невалидных полей.
+// Реализует gqlerrors.ExtendedError, поэтому клиент получает extensions.code
+type Error struct {
+	Fields []FieldError
+}
+
+// Error возвращает сообщение первой ошибки
+func (e *Error) Error() string {
+	if len(e.Fields) == 0 {
+		return "ошибка валидации"
+	}
+	return strings.Join(e.Fields[0].Path, ".") + ": " + e.Fields[0].Message
+}
+
+// Extensions возвращает код ошибки и пути невалидных полей
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":   CodeValidationFailed,
+		"fields": e.Fields,
+	}
+}
+
+// ValidatePost проверяет заголовок и текст поста
+func (l Limits) ValidatePost(title, content string) error {
+	var fields []FieldError
+
+	if msg := checkLine(title, l.MaxPostTitleLength); msg != "" {
+		fields = append(fields, FieldError{Path: []string{"title"}, Message: msg})
+	}
+	if msg := checkText(content, l.MaxPostContentLength); msg != "" {
+		fields = append(fields, FieldError{Path: []string{"content"}, Message: msg})
+	}
+
+	if len(fields) > 0 {
+		return &Error{Fields: fields}
+	}
+	return nil
+}
+
+// ValidatePostUpdate проверяет частичное обновление поста: nil-поля не меняются и не проверяются.
+// prefix - путь к input-объекту мутации
+func (l Limits) ValidatePostUpdate(title, content *string, prefix ...string) error {
+	var fields []FieldError
+
+	if title != nil {
+		if msg := checkLine(*title, l.MaxPostTitleLength); msg != "" {
+			fields = append(fields, FieldError{Path: withField(prefix, "title"), Message: msg})
+		}
+	}
+	if content != nil {
+		if msg := checkText(*content, l.MaxPostContentLength); msg != "" {
+			fields = append(fields, FieldError{Path: withField(prefix, "content"), Message: msg})
+		}
+	}
+
+	if len(fields) > 0 {
+		return &Error{Fields: fields}
+	}
+	return nil
+}
+
+// ValidateComment проверяет текст комментария.
+// path - путь к полю content в аргументах мутации
+func (l Limits) ValidateComment(content string, path ...string) error {
+	if len(path) == 0 {
+		path = []string{"content"}
+	}
+
+	if msg := checkText(content, l.MaxCommentLength); msg != "" {
+		return &Error{Fields: []FieldError{{Path: path, Message: msg}}}
+	}
+	return nil
+}
+
+// ValidateAuthor проверяет имя автора по тем же правилам, что и заголовок поста
+func (l Limits) ValidateAuthor(author string, path ...string) error {
+	if len(path) == 0 {
+		path = []string{"author"}
+	}
+
+	if msg := checkLine(author, l.MaxAuthorLength); msg != "" {
+		return &Error{Fields: []FieldError{{Path: path, Message: msg}}}
+	}
+	return nil
+}
+
+// MaxEmojiLength - максимальная длина эмодзи-реакции в символах
+// (составные эмодзи с модификаторами занимают несколько рун)
+const MaxEmojiLength = 16
+
+// ValidateEmoji проверяет эмодзи-реакцию: одна короткая строка без пробелов
+func ValidateEmoji(emoji string, path ...string) error {
+	if len(path) == 0 {
+		path = []string{"emoji"}
+	}
+
+	msg := checkLine(emoji, MaxEmojiLength)
+	if msg == "" && strings.ContainsFunc(emoji, unicode.IsSpace) {
+		msg = "реакция не может содержать пробелы"
+	}
+	if msg != "" {
+		return &Error{Fields: []FieldError{{Path: path, Message: msg}}}
+	}
+	return nil
+}
+
+// withField добавляет имя поля к пути input-объекта
+func withField(prefix []string, field string) []string {
+	path := make([]string, 0, len(prefix)+1)
+	path = append(path, prefix...)
+	return append(path, field)
+}
+
+// checkText проверяет произвольный текст: UTF-8, непустоту и длину.
+// Возвращает пустую строку, если текст валиден
+func checkText(text string, maxLength int) string {
+	if !utf8.ValidString(text) {
+		return "текст содержит некорректный UTF-8"
+	}
+	if strings.TrimSpace(text) == "" {
+		return "текст не может быть пустым"
+	}
+	if maxLength > 0 && utf8.RuneCountInString(text) > maxLength {
+		return fmt.Sprintf("текст длиннее %d символов", maxLength)
+	}
+	return ""
+}
+
+// checkLine проверяет однострочное значение (заголовок, имя автора):
+// те же правила, что для текста, плюс никаких управляющих символов и краевых пробелов
+func checkLine(value string, maxLength int) string {
+	if msg := checkText(value, maxLength); msg != "" {
+		return msg
+	}
+	for _, r := range value {
+		if unicode.IsControl(r) {
+			return "значение не может содержать переводы строк и управляющие символы"
+		}
+	}
+	if strings.TrimSpace(value) != value {
+		return "значение не может начинаться или заканчиваться пробелами"
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateComment(t *testing.T) {
+	limits := DefaultLimits()
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"обычный текст", "Привет", false},
+		{"ровно лимит", strings.Repeat("я", 2000), false},
+		{"длиннее лимита", strings.Repeat("я", 2001), true},
+		{"пустой", "", true},
+		{"только пробелы", "  \n\t ", true},
+		{"битый UTF-8", "abc\xff", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := limits.ValidateComment(tt.content, "input", "content")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Ожидали ошибку: %v, получили %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLimits_WithDefaults(t *testing.T) {
+	defaults := DefaultLimits()
+
+	// Заданное поле сохраняется, остальные берутся по умолчанию
+	limits := Limits{MaxCommentLength: 500}.WithDefaults()
+	want := defaults
+	want.MaxCommentLength = 500
+	if limits != want {
+		t.Errorf("Ожидали %+v, получили %+v", want, limits)
+	}
+
+	if limits := (Limits{}).WithDefaults(); limits != defaults {
+		t.Errorf("Ожидали ограничения по умолчанию, получили %+v", limits)
+	}
+}
+
+func TestValidateEmoji(t *testing.T) {
+	tests := []struct {
+		name    string
+		emoji   string
+		wantErr bool
+	}{
+		{"простой", "👍", false},
+		{"с модификатором", "👍🏽", false},
+		{"семья", "👨‍👩‍👧‍👦", false},
+		{"пустой", "", true},
+		{"с пробелом", "👍 👎", true},
+		{"длиннее лимита", strings.Repeat("👍", MaxEmojiLength+1), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEmoji(tt.emoji, "emoji")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Ожидали ошибку: %v, получили %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidatePost_Extensions(t *testing.T) {
+	limits := DefaultLimits()
+
+	// Заголовок с переводом строки и пустой текст - две ошибки
+	err := limits.ValidatePost("Заголовок\nвторая строка", "")
+
+	var validationErr *Error
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Ожидали *Error, получили %v", err)
+	}
+	if len(validationErr.Fields) != 2 {
+		t.Fatalf("Ожидали 2 ошибки полей, получили %d", len(validationErr.Fields))
+	}
+
+	extensions := validationErr.Extensions()
+	if extensions["code"] != CodeValidationFailed {
+		t.Errorf("Ожидали код %s, получили %v", CodeValidationFailed, extensions["code"])
+	}
+	if validationErr.Fields[0].Path[0] != "title" || validationErr.Fields[1].Path[0] != "content" {
+		t.Errorf("Неверные пути полей: %+v", validationErr.Fields)
+	}
+}
@@ -1,20 +0,0 @@
-DROP TABLE IF EXISTS comments;
-DROP TABLE IF EXISTS posts;
-
-CREATE TABLE posts (
-    id VARCHAR(50) PRIMARY KEY,
-    title TEXT NOT NULL,
-    content TEXT NOT NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
-CREATE TABLE comments (
-    id VARCHAR(50) PRIMARY KEY,
-    post_id VARCHAR(50) NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
-    parent_id VARCHAR(50) REFERENCES comments(id) ON DELETE CASCADE,
-    content TEXT NOT NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
-CREATE INDEX idx_comments_post_id ON comments(post_id);
-CREATE INDEX idx_comments_parent_id ON comments(parent_id);
\ No newline at end of file